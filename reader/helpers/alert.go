@@ -1,6 +1,11 @@
 package helpers
 
-import "github.com/veverkap/logtop/reader/structs"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
 
 // ErrorState int for "enum"
 type ErrorState int
@@ -40,7 +45,12 @@ var ThresholdRate float64
 
 // CalculateErrorState checks the trailing events and calculates whether the threshold has been met
 func CalculateErrorState(events []structs.LogEvent, alertThresholdDuration int, alertThreshold int) ErrorState {
-	ThresholdEventCount = len(structs.TrailingEvents(events, int64(alertThresholdDuration)))
+	return CalculateErrorStateFromCount(len(structs.TrailingEvents(events, int64(alertThresholdDuration))), alertThresholdDuration, alertThreshold)
+}
+
+// CalculateErrorStateFromCount calculates whether the threshold has been met by the count events of the threshold period
+func CalculateErrorStateFromCount(count int, alertThresholdDuration int, alertThreshold int) ErrorState {
+	ThresholdEventCount = count
 	perSecond := ThresholdEventCount / alertThresholdDuration
 
 	ThresholdRate = float64(ThresholdEventCount*1.0) / float64(alertThresholdDuration*1.0)
@@ -85,3 +95,40 @@ func CalculateErrorState(events []structs.LogEvent, alertThresholdDuration int,
 	}
 	return Default
 }
+
+// AlertFilterRule is a parsed -alertFilter, only the events whose Field is Value count towards the alert
+type AlertFilterRule struct {
+	Field string
+	Value string
+}
+
+/*
+ParseAlertFilter reads a filter written as field=value (like agent_class=bot) where field is one of
+structs.EventFields or extraFields (the variables of -logFormat). An empty filter gives a nil rule, which keeps every
+event
+*/
+func ParseAlertFilter(filter string, extraFields []string) (*AlertFilterRule, error) {
+	if filter == "" {
+		return nil, nil
+	}
+	pieces := strings.SplitN(filter, "=", 2)
+	if len(pieces) != 2 || strings.TrimSpace(pieces[0]) == "" {
+		return nil, fmt.Errorf("Bad alert filter %q (expected field=value)", filter)
+	}
+	rule := &AlertFilterRule{Field: strings.TrimSpace(pieces[0]), Value: pieces[1]}
+	if structs.IsEventField(rule.Field) {
+		return rule, nil
+	}
+	for _, field := range extraFields {
+		if field == rule.Field {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown alert filter field %q (expected one of %s or a variable of -logFormat)",
+		rule.Field, strings.Join(structs.EventFields, ", "))
+}
+
+// Matches reports whether the event counts towards the alert, so an alert can be raised on one dimension of the traffic
+func (rule *AlertFilterRule) Matches(event structs.LogEvent) bool {
+	return event.Field(rule.Field) == rule.Value
+}
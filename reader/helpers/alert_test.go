@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -237,3 +238,62 @@ func TestCalculateErrorStateFromRecovered(t *testing.T) {
 		})
 	}
 }
+
+func TestAlertFilterRuleMatches(t *testing.T) {
+	bot := structs.LogEvent{AgentClass: structs.AgentBot}
+	browser := structs.LogEvent{AgentClass: structs.AgentBrowser}
+	events := []structs.LogEvent{bot, browser, bot}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   int
+	}{
+		{name: "bots", filter: "agent_class=bot", want: 2},
+		{name: "browsers", filter: "agent_class=browser", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseAlertFilter(tt.filter, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := 0
+			for _, event := range events {
+				if rule.Matches(event) {
+					got++
+				}
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = true for %d events, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAlertFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    *AlertFilterRule
+		wantErr bool
+	}{
+		{filter: "", want: nil},
+		{filter: "agent_class=bot", want: &AlertFilterRule{Field: "agent_class", Value: "bot"}},
+		{filter: "query=a=b", want: &AlertFilterRule{Field: "query", Value: "a=b"}},
+		{filter: "upstream_addr=10.0.0.2", want: &AlertFilterRule{Field: "upstream_addr", Value: "10.0.0.2"}},
+		{filter: "agent_class", wantErr: true},
+		{filter: "=bot", wantErr: true},
+		{filter: "agentclas=bot", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := ParseAlertFilter(tt.filter, []string{"upstream_addr"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAlertFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAlertFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
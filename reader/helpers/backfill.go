@@ -0,0 +1,129 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// StartFrom describes where the log files that exist when logtop starts are read from
+type StartFrom struct {
+	// Beginning replays the whole file
+	Beginning bool
+	// Last replays the lines from the last Last of the file (by timestamp), 0 starts at the end
+	Last time.Duration
+}
+
+// ParseStartFrom reads the -from flag: "beginning", "end" or a duration like "10m"
+func ParseStartFrom(value string) (StartFrom, error) {
+	switch strings.ToLower(value) {
+	case "beginning", "start":
+		return StartFrom{Beginning: true}, nil
+	case "end", "":
+		return StartFrom{}, nil
+	}
+
+	last, err := time.ParseDuration(value)
+	if err != nil || last <= 0 {
+		return StartFrom{}, fmt.Errorf("Bad -from %q (expected beginning, end or a duration like 10m)", value)
+	}
+	return StartFrom{Last: last}, nil
+}
+
+// end reports whether nothing is replayed
+func (from StartFrom) end() bool {
+	return !from.Beginning && from.Last <= 0
+}
+
+/*
+backlogOffset returns where reading location starts for from: its beginning, or the first line dated within the
+last from.Last. The existing lines after it are then read by the tail like new ones, so a large backlog is
+streamed rather than held in memory
+*/
+func backlogOffset(location string, from StartFrom, newParser structs.ParserFactory) (int64, error) {
+	if from.Beginning {
+		return 0, nil
+	}
+
+	file, err := os.Open(location)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return findOffsetSince(file, info.Size(), time.Now().Add(-from.Last), newParser)
+}
+
+/*
+findOffsetSince binary searches the (time ordered) file for the first line dated at or after since and
+returns where it starts. Lines that cannot be parsed are skipped over, and when no line can be dated
+(like a W3C log whose #Fields header is far away) the whole file is replayed
+*/
+func findOffsetSince(file *os.File, size int64, since time.Time, newParser structs.ParserFactory) (int64, error) {
+	parser := newParser()
+	low, high := int64(0), size
+	for low < high {
+		middle := low + (high-low)/2
+		start, next, date, found, err := firstDatedLine(file, middle, high, parser)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case !found:
+			high = middle
+		case date.Before(since):
+			low = next
+		default:
+			high = start
+		}
+	}
+	return low, nil
+}
+
+/*
+firstDatedLine finds the first line starting at or after offset (and before limit) that parses with a date,
+returning where it starts, where the following line starts and its date
+*/
+func firstDatedLine(file *os.File, offset int64, limit int64, parser structs.Parser) (int64, int64, time.Time, bool, error) {
+	// step back one byte so a line starting exactly at offset is not skipped
+	start := offset
+	if start > 0 {
+		start--
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, time.Time{}, false, err
+	}
+	reader := bufio.NewReader(file)
+
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err != nil {
+			return 0, 0, time.Time{}, false, nil
+		}
+		start += int64(len(skipped))
+	}
+
+	for start < limit {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			// the last line is still being written
+			return 0, 0, time.Time{}, false, nil
+		}
+		next := start + int64(len(text))
+		if event, err := parser.Parse(strings.TrimRight(text, "\r\n")); err == nil && !event.Date.IsZero() {
+			return start, next, event.Date, true, nil
+		}
+		start = next
+	}
+	return 0, 0, time.Time{}, false, nil
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseStartFrom(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StartFrom
+		wantErr bool
+	}{
+		{"beginning", StartFrom{Beginning: true}, false},
+		{"start", StartFrom{Beginning: true}, false},
+		{"end", StartFrom{}, false},
+		{"", StartFrom{}, false},
+		{"10m", StartFrom{Last: 10 * time.Minute}, false},
+		{"1h30m", StartFrom{Last: 90 * time.Minute}, false},
+		{"-5m", StartFrom{}, true},
+		{"yesterday", StartFrom{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseStartFrom(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseStartFrom() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseStartFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBacklogOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// one line a minute for the last hour, the last one still being written
+	location := filepath.Join(dir, "access.log")
+	now := time.Now()
+	lines := make([]string, 0)
+	for minutes := 60; minutes > 0; minutes-- {
+		date := now.Add(-time.Duration(minutes) * time.Minute).Format("02/Jan/2006:15:04:05 -0700")
+		line := "127.0.0.1 - james [" + date + "] \"GET /report HTTP/1.0\" 200 123"
+		lines = append(lines, line)
+		appendLine(t, location, line)
+	}
+	partial := "127.0.0.1 - james [" + now.Format("02/Jan/2006:15:04:05 -0700") + "] \"GET /rep"
+	f, err := os.OpenFile(location, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(partial)
+	f.Close()
+
+	// offsets[i] is where lines[i] starts, offsets[60] where the partial line does
+	offsets := []int64{0}
+	for _, line := range lines {
+		offsets = append(offsets, offsets[len(offsets)-1]+int64(len(line)+1))
+	}
+
+	tests := []struct {
+		name  string
+		from  StartFrom
+		count int
+	}{
+		{"beginning", StartFrom{Beginning: true}, 60},
+		// half a minute of slack so the line from 10 minutes ago is not older than the cutoff by the time we read it
+		{"last 10 minutes", StartFrom{Last: 10*time.Minute + 30*time.Second}, 10},
+		{"last 90 minutes", StartFrom{Last: 90 * time.Minute}, 60},
+		{"last second", StartFrom{Last: time.Second}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := backlogOffset(location, tt.from, clfParser)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := offsets[len(lines)-tt.count]; offset != want {
+				t.Errorf("backlogOffset() = %v, want %v (%d lines)", offset, want, tt.count)
+			}
+		})
+	}
+
+	// the backlog comes through the tail, followed by what is written next
+	tailed, err := TailFiles([]string{location}, StartFrom{Last: 2*time.Minute + 30*time.Second}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range lines[len(lines)-2:] {
+		if got := receiveLine(t, tailed); got.Text != want {
+			t.Errorf("TailFiles() line = %q, want %q", got.Text, want)
+		}
+	}
+	// give the tail a moment to reach the partial line before finishing it
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, location, "")
+	if got := receiveLine(t, tailed); got.Text != partial {
+		t.Errorf("TailFiles() line = %q, want the partial line %q once finished", got.Text, partial)
+	}
+}
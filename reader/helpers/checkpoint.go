@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// CheckpointInterval is how often the checkpoints are written to the -stateFile
+var CheckpointInterval = 5 * time.Second
+
+// Checkpoint records how far a file has been read
+type Checkpoint struct {
+	// Inode identifies the file, so a file that has since been rotated away is not resumed (0 when unknown)
+	Inode uint64 `json:"inode"`
+	// Offset is where the line after the last one ingested starts
+	Offset int64 `json:"offset"`
+}
+
+// checkpointStore keeps the checkpoints TailFiles resumes from along with how far every followed file has been read
+type checkpointStore struct {
+	mutex   sync.Mutex
+	resume  map[string]Checkpoint
+	offsets map[string]Checkpoint
+	// status says when the checkpoints were last saved (or why they could not be) for the debug table
+	status string
+}
+
+// FileCheckpoints are the checkpoints of the followed files, they are only resumed from once loaded from a -stateFile
+var FileCheckpoints = &checkpointStore{
+	resume:  make(map[string]Checkpoint),
+	offsets: make(map[string]Checkpoint),
+}
+
+// Load reads the checkpoints to resume from out of stateFile, a stateFile that does not exist yet is fine
+func (store *checkpointStore) Load(stateFile string) error {
+	content, err := ioutil.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	checkpoints := make(map[string]Checkpoint)
+	if err := json.Unmarshal(content, &checkpoints); err != nil {
+		return fmt.Errorf("Bad state file %s: %v", stateFile, err)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.resume = checkpoints
+	return nil
+}
+
+/*
+Save writes the checkpoint of every followed file to stateFile. The inode saved is the one of the file that was read,
+not whatever is at its location by now. It is written to a temporary file that is then renamed over stateFile, so a
+crash while saving leaves the previous checkpoints in place
+*/
+func (store *checkpointStore) Save(stateFile string) error {
+	store.mutex.Lock()
+	checkpoints := make(map[string]Checkpoint)
+	for location, checkpoint := range store.offsets {
+		checkpoints[location] = checkpoint
+	}
+	store.mutex.Unlock()
+
+	content, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary, err := ioutil.TempFile(filepath.Dir(stateFile), filepath.Base(stateFile)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(content); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), stateFile)
+}
+
+// SaveEvery saves the checkpoints to stateFile every interval, keeping Status up to date
+func (store *checkpointStore) SaveEvery(stateFile string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		store.saveWithStatus(stateFile)
+	}
+}
+
+// saveWithStatus saves the checkpoints to stateFile and records how that went for Status
+func (store *checkpointStore) saveWithStatus(stateFile string) {
+	status := "saved at " + time.Now().Format("15:04:05")
+	if err := store.Save(stateFile); err != nil {
+		status = fmt.Sprintf("could not save: %v", err)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.status = status
+}
+
+// Status says when the checkpoints were last saved (or why they could not be), for the debug table
+func (store *checkpointStore) Status() string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.status
+}
+
+/*
+resumeOffset returns where to carry on reading location from when it has a checkpoint: the checkpoint itself when the
+file is the same one (by inode) and at least as long, otherwise the file has been rotated or truncated while we were
+not running and everything in it is new, so it is read from its start
+*/
+func (store *checkpointStore) resumeOffset(location string) (int64, bool) {
+	store.mutex.Lock()
+	checkpoint, ok := store.resume[location]
+	store.mutex.Unlock()
+	if !ok {
+		return 0, false
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		return 0, false
+	}
+	if info.Size() < checkpoint.Offset {
+		return 0, true
+	}
+	if inode, ok := fileIdentity(info); ok && checkpoint.Inode != 0 && inode != checkpoint.Inode {
+		return 0, true
+	}
+	return checkpoint.Offset, true
+}
+
+// set records that location has been read up to checkpoint
+func (store *checkpointStore) set(location string, checkpoint Checkpoint) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.offsets[location] = checkpoint
+}
+
+/*
+ingested checkpoints a line of a followed file once it has been ingested (or a Rotated one once it has been shown),
+lines from anywhere else are left alone
+*/
+func (store *checkpointStore) ingested(line structs.SourceLine) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, ok := store.offsets[line.Source]; !ok || line.EOF {
+		return
+	}
+	store.offsets[line.Source] = Checkpoint{Inode: line.Inode, Offset: line.Offset}
+}
@@ -0,0 +1,203 @@
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestCheckpointStoreSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "first line")
+
+	// the inode is the one recorded with the offset rather than the one at location when saving
+	store := &checkpointStore{resume: make(map[string]Checkpoint), offsets: make(map[string]Checkpoint)}
+	store.set(location, Checkpoint{Inode: 42, Offset: 11})
+
+	stateFile := filepath.Join(dir, "logtop.state")
+	if err := store.Save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &checkpointStore{resume: make(map[string]Checkpoint), offsets: make(map[string]Checkpoint)}
+	if err := loaded.Load(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Checkpoint{location: {Inode: 42, Offset: 11}}
+	if !reflect.DeepEqual(loaded.resume, want) {
+		t.Errorf("Load() = %v, want %v", loaded.resume, want)
+	}
+
+	if err := loaded.Load(filepath.Join(dir, "missing.state")); err != nil {
+		t.Errorf("Load() error = %v for a state file that does not exist yet", err)
+	}
+	ioutil.WriteFile(stateFile, []byte("not json"), 0644)
+	if err := loaded.Load(stateFile); err == nil {
+		t.Error("Load() expected an error for a bad state file")
+	}
+}
+
+func TestCheckpointStoreResumeOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "first line")
+	appendLine(t, location, "second line")
+	info, err := os.Stat(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inode, hasIdentity := fileIdentity(info)
+	rotatedOffset := int64(0)
+	if !hasIdentity {
+		// without inodes a rotated file cannot be told apart
+		rotatedOffset = 11
+	}
+
+	tests := []struct {
+		name       string
+		checkpoint Checkpoint
+		want       int64
+		wantOK     bool
+	}{
+		{"same file", Checkpoint{Inode: inode, Offset: 11}, 11, true},
+		{"unknown inode", Checkpoint{Offset: 11}, 11, true},
+		{"truncated since", Checkpoint{Inode: inode, Offset: 100}, 0, true},
+		{"rotated since", Checkpoint{Inode: inode + 1, Offset: 11}, rotatedOffset, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &checkpointStore{resume: map[string]Checkpoint{location: tt.checkpoint}}
+			got, ok := store.resumeOffset(location)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("resumeOffset() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	store := &checkpointStore{resume: make(map[string]Checkpoint)}
+	if _, ok := store.resumeOffset(location); ok {
+		t.Error("resumeOffset() resumed a file without a checkpoint")
+	}
+}
+
+func TestCheckpointStoreIngested(t *testing.T) {
+	store := &checkpointStore{offsets: map[string]Checkpoint{"access.log": {Inode: 42, Offset: 11}}}
+
+	tests := []struct {
+		name string
+		line structs.SourceLine
+		want map[string]Checkpoint
+	}{
+		{
+			"line of a followed file",
+			structs.SourceLine{Source: "access.log", Text: "second line", Inode: 42, Offset: 23},
+			map[string]Checkpoint{"access.log": {Inode: 42, Offset: 23}},
+		},
+		{
+			"rotated",
+			structs.SourceLine{Source: "access.log", Text: RotatedRenamed, Rotated: true, Inode: 43},
+			map[string]Checkpoint{"access.log": {Inode: 43}},
+		},
+		{
+			"not a followed file",
+			structs.SourceLine{Source: StdinLocation, Text: "from stdin"},
+			map[string]Checkpoint{"access.log": {Inode: 43}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.ingested(tt.line)
+			if !reflect.DeepEqual(store.offsets, tt.want) {
+				t.Errorf("offsets = %v, want %v", store.offsets, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointStoreStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &checkpointStore{offsets: make(map[string]Checkpoint)}
+	store.saveWithStatus(filepath.Join(dir, "logtop.state"))
+	if got := store.Status(); !strings.HasPrefix(got, "saved at ") {
+		t.Errorf("Status() = %q, want it saved", got)
+	}
+	store.saveWithStatus(filepath.Join(dir, "missing", "logtop.state"))
+	if got := store.Status(); !strings.HasPrefix(got, "could not save: ") {
+		t.Errorf("Status() = %q, want why it could not be saved", got)
+	}
+}
+
+func TestTailFilesResumesFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "read before the restart")
+	appendLine(t, location, "written while we were down")
+
+	// the state file left behind by the previous run
+	stateFile := filepath.Join(dir, "logtop.state")
+	ioutil.WriteFile(stateFile, []byte(`{"`+location+`": {"offset": 24}}`), 0644)
+	if err := FileCheckpoints.Load(stateFile); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := receiveLine(t, lines)
+	if got.Text != "written while we were down" {
+		t.Errorf("got %v, want the line written while we were down", got)
+	}
+
+	// the offset only moves on once the line has been ingested
+	inode := inodeOf(t, location)
+	checkpointOf := func() Checkpoint {
+		FileCheckpoints.mutex.Lock()
+		defer FileCheckpoints.mutex.Unlock()
+		return FileCheckpoints.offsets[location]
+	}
+	if want := (Checkpoint{Inode: inode, Offset: 24}); checkpointOf() != want {
+		t.Errorf("checkpoint = %v before the line was ingested, want %v", checkpointOf(), want)
+	}
+	FileCheckpoints.ingested(got)
+	size := int64(len("read before the restart\nwritten while we were down\n"))
+	if want := (Checkpoint{Inode: inode, Offset: size}); checkpointOf() != want {
+		t.Errorf("checkpoint = %v once the line was ingested, want %v", checkpointOf(), want)
+	}
+}
+
+// inodeOf returns the inode of the file at location, 0 where there are none
+func inodeOf(t *testing.T, location string) uint64 {
+	info, err := os.Stat(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inode, _ := fileIdentity(info)
+	return inode
+}
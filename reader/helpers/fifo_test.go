@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestTailFilesFIFO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.fifo")
+	if err := syscall.Mkfifo(location, 0644); err != nil {
+		t.Skipf("named pipes are not supported: %v", err)
+	}
+
+	lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every writer is read in turn
+	for _, text := range []string{"first writer", "second writer"} {
+		writer, err := os.OpenFile(location, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.WriteString(text + "\n")
+		writer.Close()
+
+		if got := receiveLine(t, lines); got != (structs.SourceLine{Source: location, Text: text}) {
+			t.Errorf("got %v", got)
+		}
+	}
+}
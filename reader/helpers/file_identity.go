@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package helpers
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the inode of the file, which stays the same while a file is renamed but not when it is recreated
+func fileIdentity(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Ino), true
+}
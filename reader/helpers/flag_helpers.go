@@ -1,6 +1,24 @@
 package helpers
 
-import "flag"
+import (
+	"flag"
+	"log"
+	"strings"
+)
+
+// stringSlice is a flag.Value collecting every use of a repeatable flag
+type stringSlice []string
+
+// String is part of flag.Value
+func (values *stringSlice) String() string {
+	return strings.Join(*values, ", ")
+}
+
+// Set is part of flag.Value, it is called for every use of the flag
+func (values *stringSlice) Set(value string) error {
+	*values = append(*values, value)
+	return nil
+}
 
 // AlertThreshold represents "Number of requests per second maximum for alert"
 var AlertThreshold int
@@ -8,13 +26,128 @@ var AlertThreshold int
 // AlertThresholdDuration represents "Duration in seconds of sampling period for alerts"
 var AlertThresholdDuration int
 
-// LogFileLocation represents "Location of log file to parse"
-var LogFileLocation string
+// LogFileLocations represents "Location of log file to parse, may be a glob and may be repeated"
+var LogFileLocations stringSlice
+
+// Format represents "Name of the parser used to read each log line"
+var Format string
+
+// LogFormat represents "Custom nginx log_format or Apache LogFormat string used to parse each line (implies -format custom)"
+var LogFormat string
+
+// JSONFields represents "Comma separated field=key mappings used by -format json (e.g. date=ts,verb=http.request.method)"
+var JSONFields string
+
+// RejectFile represents "Location of a file to append lines that could not be parsed to"
+var RejectFile string
+
+// RejectSampleSize represents "Number of rejected lines kept for the rejected lines panel"
+var RejectSampleSize int
+
+// Timezone represents "Time zone used for timestamps without one (e.g. UTC, America/New_York)"
+var Timezone string
+
+// ResponseTimeUnit represents "Unit of the response time at the end of clf/combined lines and of W3C time-taken: s, ms or us"
+var ResponseTimeUnit string
+
+// SectionDepth represents "Number of path segments that make up a section"
+var SectionDepth int
+
+// SectionRules represents "Section rewrite rule written as regex=>section, tried in order (repeatable)"
+var SectionRules stringSlice
+
+// NormalizeIDs represents "Replace numeric and UUID path segments with :id when working out sections"
+var NormalizeIDs bool
+
+// UserAgentRulesFile represents "Location of a user agent rule table (class<TAB>family<TAB>regex per line) replacing the built in one"
+var UserAgentRulesFile string
 
-// ParseFlags loads the flags passed at the command line or sets defaults
-func ParseFlags() {
+// AlertFilter represents "Only count events matching field=value (e.g. agent_class=bot) towards the alert"
+var AlertFilter string
+
+// alertFilterRule is AlertFilter parsed by LoadAlertFilter, nil when every event counts towards the alert
+var alertFilterRule *AlertFilterRule
+
+/*
+LoadAlertFilter parses AlertFilter once the fields of -logFormat (extraFields) are known, a filter on a field no event
+has would otherwise never match and keep the alert quiet
+*/
+func LoadAlertFilter(extraFields []string) error {
+	rule, err := ParseAlertFilter(AlertFilter, extraFields)
+	if err != nil {
+		return err
+	}
+	alertFilterRule = rule
+	return nil
+}
+
+// Stdin represents "Read the log from stdin (same as -logFileLocation -)"
+var Stdin bool
+
+// From represents "Where to start reading existing log files: beginning, end or a duration like 10m"
+var From string
+
+// Speed represents "Speed of reader replay: a multiplier like 1x or 10x, or max"
+var Speed string
+
+// SyslogAddresses represents "Address to receive syslog messages on, as udp://host:port, tcp://host:port or host:port for both (repeatable)"
+var SyslogAddresses stringSlice
+
+// ListenAddress represents "Address to serve the HTTP /ingest endpoint on (e.g. :8080)"
+var ListenAddress string
+
+// StateFile represents "Location of a file to checkpoint how far each log file has been read to, so a restart carries on from there"
+var StateFile string
+
+// GroupBy represents "Field the statistics table groups by (section, path, host, user...), fields other than section are counted with a top-K sketch"
+var GroupBy string
+
+// TopKSize represents "Number of values each top-K sketch keeps counters for (its memory budget), counts are exact below it"
+var TopKSize int
+
+/*
+ParseFlags loads the flags passed at the command line or sets defaults. replay is set for reader replay, which
+takes the files to replay as arguments too
+*/
+func ParseFlags(replay bool) {
 	flag.IntVar(&AlertThreshold, "threshold", 10, "Number of requests per second maximum for alert")
 	flag.IntVar(&AlertThresholdDuration, "thresholdDuration", 120, "Duration in seconds of sampling period for alerts")
-	flag.StringVar(&LogFileLocation, "logFileLocation", "/tmp/access.log", "Location of log file to parse")
+	flag.Var(&LogFileLocations, "logFileLocation", "Location of log file to parse, may be a glob and may be repeated (default \"/tmp/access.log\")")
+	flag.StringVar(&Format, "format", "clf", "Name of the parser used to read each log line")
+	flag.StringVar(&LogFormat, "logFormat", "", "Custom nginx log_format or Apache LogFormat string used to parse each line (implies -format custom)")
+	flag.StringVar(&JSONFields, "jsonFields", "", "Comma separated field=key mappings used by -format json (e.g. date=ts,verb=http.request.method)")
+	flag.StringVar(&RejectFile, "rejectFile", "", "Location of a file to append lines that could not be parsed to")
+	flag.IntVar(&RejectSampleSize, "rejectSample", 100, "Number of rejected lines kept for the rejected lines panel")
+	flag.StringVar(&Timezone, "timezone", "Local", "Time zone used for timestamps without one (e.g. UTC, America/New_York)")
+	flag.StringVar(&ResponseTimeUnit, "responseTimeUnit", "s", "Unit of the response time at the end of clf/combined lines and of W3C time-taken: s, ms or us")
+	flag.IntVar(&SectionDepth, "sectionDepth", 1, "Number of path segments that make up a section")
+	flag.Var(&SectionRules, "sectionRule", "Section rewrite rule written as regex=>section, tried in order (repeatable)")
+	flag.BoolVar(&NormalizeIDs, "normalizeIDs", false, "Replace numeric and UUID path segments with :id when working out sections")
+	flag.StringVar(&UserAgentRulesFile, "userAgentRules", "", "Location of a user agent rule table (class<TAB>family<TAB>regex per line) replacing the built in one")
+	flag.StringVar(&AlertFilter, "alertFilter", "", "Only count events matching field=value (e.g. agent_class=bot) towards the alert")
+	flag.BoolVar(&Stdin, "stdin", false, "Read the log from stdin (same as -logFileLocation -)")
+	flag.StringVar(&From, "from", "end", "Where to start reading existing log files: beginning, end or a duration like 10m")
+	flag.StringVar(&Speed, "speed", "1x", "Speed of reader replay: a multiplier like 1x or 10x, or max")
+	flag.Var(&SyslogAddresses, "syslog", "Address to receive syslog messages on, as udp://host:port, tcp://host:port or host:port for both (repeatable)")
+	flag.StringVar(&ListenAddress, "listen", "", "Address to serve the HTTP /ingest endpoint on (e.g. :8080)")
+	flag.StringVar(&StateFile, "stateFile", "", "Location of a file to checkpoint how far each log file has been read to, so a restart carries on from there")
+	flag.StringVar(&GroupBy, "groupBy", "section", "Field the statistics table groups by (section, path, host, user...), fields other than section are counted with a top-K sketch")
+	flag.IntVar(&TopKSize, "topK", 1000, "Number of values each top-K sketch keeps counters for (its memory budget), counts are exact below it")
 	flag.Parse()
+
+	if replay {
+		LogFileLocations = append(LogFileLocations, flag.Args()...)
+	} else if flag.NArg() > 0 {
+		// a stray argument would otherwise be ignored, leaving the user wondering why the file is not followed
+		log.Fatalf("Unexpected argument %q (use -logFileLocation to follow a file)", flag.Arg(0))
+	}
+
+	if Stdin {
+		LogFileLocations = append(LogFileLocations, StdinLocation)
+	}
+
+	// when receiving syslog or HTTP there is no need to follow a file as well
+	if len(LogFileLocations) == 0 && len(SyslogAddresses) == 0 && ListenAddress == "" {
+		LogFileLocations = stringSlice{"/tmp/access.log"}
+	}
 }
@@ -0,0 +1,148 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// IngestQueueSize is the number of lines pushed to /ingest waiting to be parsed before batches are turned away
+var IngestQueueSize = 10000
+
+// maxIngestBatchSize is the largest body /ingest reads
+const maxIngestBatchSize = 16 * 1024 * 1024
+
+// How long a request to /ingest may take to be read and answered, so a stalled client does not hold on to its connection
+const (
+	ingestReadTimeout  = time.Minute
+	ingestWriteTimeout = 10 * time.Second
+)
+
+// httpSourcePrefix starts the Source of the events pushed to /ingest, it is followed by the address they came from
+const httpSourcePrefix = "http:"
+
+// ingestHandler queues the lines of the batches POSTed to /ingest
+type ingestHandler struct {
+	// mutex makes checking for room and queueing a batch one step, so a batch is queued whole or not at all
+	mutex sync.Mutex
+	lines chan structs.SourceLine
+}
+
+/*
+ListenHTTP serves /ingest on address and sends the lines of every batch POSTed to it to the returned channel.
+A batch is either newline delimited or a JSON array of lines (JSON objects are passed on as they are, for -format
+json). When the queue is full the batch is refused with a 429 so the sender backs off and tries again, a batch
+with more lines than the queue holds or a body over maxIngestBatchSize is refused with a 413 as it never fits
+*/
+func ListenHTTP(address string) (<-chan structs.SourceLine, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Could not listen for HTTP on %s: %v", address, err)
+	}
+
+	handler := &ingestHandler{lines: make(chan structs.SourceLine, IngestQueueSize)}
+	mux := http.NewServeMux()
+	mux.Handle("/ingest", handler)
+	server := &http.Server{Handler: mux, ReadTimeout: ingestReadTimeout, WriteTimeout: ingestWriteTimeout}
+	go server.Serve(listener)
+	return handler.lines, nil
+}
+
+// ServeHTTP is part of http.Handler
+func (handler *ingestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "Lines must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(writer, request.Body, maxIngestBatchSize)); err != nil {
+		// MaxBytesReader only fails after handing over all it allows, anything short of that is the client's doing
+		status := http.StatusBadRequest
+		if body.Len() >= maxIngestBatchSize {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(writer, fmt.Sprintf("Could not read batch: %v", err), status)
+		return
+	}
+	texts, err := ingestBatch(body.Bytes())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source := httpSourcePrefix + request.RemoteAddr
+	if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		source = httpSourcePrefix + host
+	}
+	if len(texts) > cap(handler.lines) {
+		http.Error(writer, fmt.Sprintf("Batch of %d lines is larger than the ingest queue (%d lines), split it up", len(texts), cap(handler.lines)), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !handler.enqueue(source, texts) {
+		writer.Header().Set("Retry-After", "1")
+		http.Error(writer, "Ingest queue is full", http.StatusTooManyRequests)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(writer, "Accepted %d lines\n", len(texts))
+}
+
+// enqueue queues every line of the batch when there is room for all of them
+func (handler *ingestHandler) enqueue(source string, texts []string) bool {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	// only enqueue adds lines and it holds the lock, the consumer can only make more room, so the room we see is there when we send
+	if cap(handler.lines)-len(handler.lines) < len(texts) {
+		return false
+	}
+	for _, text := range texts {
+		handler.lines <- structs.SourceLine{Source: source, Text: text}
+	}
+	return true
+}
+
+// ingestBatch splits a batch into its lines, leaving out the empty ones
+func ingestBatch(body []byte) ([]string, error) {
+	texts := make([]string, 0)
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var elements []json.RawMessage
+		if err := json.Unmarshal(trimmed, &elements); err != nil {
+			return nil, fmt.Errorf("Bad JSON batch: %v", err)
+		}
+		for _, element := range elements {
+			var text string
+			if err := json.Unmarshal(element, &text); err != nil {
+				// not a string, pass the JSON on as a line of its own
+				var compacted bytes.Buffer
+				json.Compact(&compacted, element)
+				text = compacted.String()
+			}
+			if strings.TrimSpace(text) != "" {
+				texts = append(texts, text)
+			}
+		}
+		return texts, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			texts = append(texts, scanner.Text())
+		}
+	}
+	return texts, scanner.Err()
+}
@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestIngestBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{"newline delimited", "first line\r\nsecond line\n\nthird line", []string{"first line", "second line", "third line"}, false},
+		{"JSON array of lines", `["first line", "", "second line"]`, []string{"first line", "second line"}, false},
+		{"JSON array of objects", `[{"ts": 1553366693, "path": "/report"}, "second line"]`, []string{`{"ts":1553366693,"path":"/report"}`, "second line"}, false},
+		{"bad JSON", `["first line"`, nil, true},
+		{"empty", "", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ingestBatch([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ingestBatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ingestBatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngestHandler(t *testing.T) {
+	handler := &ingestHandler{lines: make(chan structs.SourceLine, 3)}
+	post := func(method string, body string) int {
+		request := httptest.NewRequest(method, "/ingest", strings.NewReader(body))
+		request.RemoteAddr = "10.0.0.1:51234"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+		queued int
+	}{
+		{"accepts a batch", http.MethodPost, "first line\nsecond line\n", http.StatusAccepted, 2},
+		{"refuses a batch that does not fit", http.MethodPost, `["third line", "fourth line"]`, http.StatusTooManyRequests, 2},
+		{"accepts a batch that fits", http.MethodPost, `["third line"]`, http.StatusAccepted, 3},
+		{"refuses a batch larger than the queue", http.MethodPost, "1\n2\n3\n4\n", http.StatusRequestEntityTooLarge, 3},
+		{"refuses a bad batch", http.MethodPost, `["fourth line"`, http.StatusBadRequest, 3},
+		{"only accepts POST", http.MethodGet, "", http.StatusMethodNotAllowed, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(tt.method, tt.body); got != tt.want {
+				t.Errorf("ServeHTTP() status = %v, want %v", got, tt.want)
+			}
+			if got := len(handler.lines); got != tt.queued {
+				t.Errorf("ServeHTTP() queued %v lines, want %v", got, tt.queued)
+			}
+		})
+	}
+
+	want := structs.SourceLine{Source: "http:10.0.0.1", Text: "first line"}
+	if got := <-handler.lines; got != want {
+		t.Errorf("first queued line = %v, want %v", got, want)
+	}
+}
+
+// failingReader is a request body whose connection breaks after a few bytes
+type failingReader struct {
+	read bool
+}
+
+func (reader *failingReader) Read(p []byte) (int, error) {
+	if reader.read {
+		return 0, errors.New("connection reset by peer")
+	}
+	reader.read = true
+	return copy(p, "first line\n"), nil
+}
+
+func TestIngestHandlerBodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body io.Reader
+		want int
+	}{
+		{"body larger than a batch", strings.NewReader(strings.Repeat("x", maxIngestBatchSize+1)), http.StatusRequestEntityTooLarge},
+		{"body that cannot be read", &failingReader{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &ingestHandler{lines: make(chan structs.SourceLine, 3)}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/ingest", tt.body))
+			if recorder.Code != tt.want {
+				t.Errorf("ServeHTTP() status = %v, want %v", recorder.Code, tt.want)
+			}
+			if got := len(handler.lines); got != 0 {
+				t.Errorf("ServeHTTP() queued %v lines, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// StdinLocation is the -logFileLocation used for reading from stdin
+const StdinLocation = "-"
+
+// StdinSource is the Source of the events read from stdin
+const StdinSource = "stdin"
+
+// maxLineLength is the longest line read from a stream, the lines of a stream that are longer are skipped
+const maxLineLength = 1024 * 1024
+
+// longLinesSkipped counts the lines of files, streams and syslog connections skipped for being longer than maxLineLength
+var longLinesSkipped int64
+
+// LongLinesSkipped returns the number of lines skipped for being longer than maxLineLength, for the debug table
+func LongLinesSkipped() int64 {
+	return atomic.LoadInt64(&longLinesSkipped)
+}
+
+/*
+ReadLines sends every line of reader (without the "\n" or "\r\n") to lines tagged with source until the reader
+is exhausted. A line longer than maxLineLength is skipped rather than ending the stream. It returns the read
+error, if any
+*/
+func ReadLines(source string, reader io.Reader, lines chan<- structs.SourceLine) error {
+	scanner := newLineScanner(reader)
+	for scanner.Scan() {
+		lines <- structs.SourceLine{Source: source, Text: scanner.Text()}
+	}
+	return scanner.Err()
+}
+
+// newLineScanner scans the lines of reader, skipping (and counting) the ones longer than maxLineLength
+func newLineScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	// one more byte than the longest line so skipLongLines sees a line that is too long before the scanner gives up
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength+1)
+	scanner.Split(skipLongLines())
+	return scanner
+}
+
+/*
+skipLongLines splits like bufio.ScanLines, but drops a line that does not fit in maxLineLength: what has been
+buffered of it is thrown away and so is the rest of it up to the next "\n"
+*/
+func skipLongLines() bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipping {
+			index := bytes.IndexByte(data, '\n')
+			if index < 0 {
+				return len(data), nil, nil
+			}
+			skipping = false
+			return index + 1, nil, nil
+		}
+
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance == 0 && err == nil && len(data) > maxLineLength {
+			skipping = true
+			atomic.AddInt64(&longLinesSkipped, 1)
+			return len(data), nil, nil
+		}
+		return advance, token, err
+	}
+}
+
+// readStdin sends the lines piped into logtop to lines, followed by an EOF SourceLine once the pipe is closed
+func readStdin(lines chan<- structs.SourceLine) {
+	ReadLines(StdinSource, os.Stdin, lines)
+	lines <- structs.SourceLine{Source: StdinSource, EOF: true}
+}
+
+/*
+readFIFO follows a named pipe. Opening a FIFO blocks until a writer shows up and reading it ends when the
+writer goes away, so it is opened again every time to wait for the next writer
+*/
+func readFIFO(location string, lines chan<- structs.SourceLine) {
+	for {
+		fifo, err := os.Open(location)
+		if err != nil {
+			lines <- structs.SourceLine{Source: location, EOF: true}
+			return
+		}
+		ReadLines(location, fifo, lines)
+		fifo.Close()
+	}
+}
+
+// isFIFO reports whether location is a named pipe
+func isFIFO(location string) bool {
+	info, err := os.Stat(location)
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// MergeLines sends the lines of every channel to the one returned, so files and listeners can feed the same UI
+func MergeLines(channels ...<-chan structs.SourceLine) <-chan structs.SourceLine {
+	if len(channels) == 1 {
+		return channels[0]
+	}
+
+	merged := make(chan structs.SourceLine)
+	for _, lines := range channels {
+		go func(lines <-chan structs.SourceLine) {
+			for line := range lines {
+				merged <- line
+			}
+		}(lines)
+	}
+	return merged
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestReadLines(t *testing.T) {
+	lines := make(chan structs.SourceLine, 10)
+	if err := ReadLines("pipe", strings.NewReader("first\nsecond\r\nlast without newline"), lines); err != nil {
+		t.Fatal(err)
+	}
+	close(lines)
+
+	got := make([]structs.SourceLine, 0)
+	for line := range lines {
+		got = append(got, line)
+	}
+	want := []structs.SourceLine{
+		{Source: "pipe", Text: "first"},
+		{Source: "pipe", Text: "second"},
+		{Source: "pipe", Text: "last without newline"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLines() = %v, want %v", got, want)
+	}
+}
+
+func TestReadLinesTooLong(t *testing.T) {
+	long := strings.Repeat("x", maxLineLength+10)
+	input := "before\n" + long + "\nafter\n" + long + "\n"
+
+	skipped := LongLinesSkipped()
+	lines := make(chan structs.SourceLine, 10)
+	if err := ReadLines("pipe", strings.NewReader(input), lines); err != nil {
+		t.Fatal(err)
+	}
+	close(lines)
+
+	got := make([]string, 0)
+	for line := range lines {
+		got = append(got, line.Text)
+	}
+	// the long lines are skipped and reading goes on
+	if want := []string{"before", "after"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLines() = %.20q, want %q", got, want)
+	}
+	if got := LongLinesSkipped() - skipped; got != 2 {
+		t.Errorf("LongLinesSkipped() went up by %d, want 2", got)
+	}
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// RejectedCounts is the number of lines that could not be parsed for each reason
+var RejectedCounts = make(map[structs.RejectReason]int)
+
+// RejectedLines holds the most recent lines that could not be parsed (at most RejectSampleSize) along with the reason
+var RejectedLines = make([]string, 0)
+
+// RecordRejectedLine counts a line that failed to parse with err, keeps it in RejectedLines and writes the raw line to writer (when not nil)
+func RecordRejectedLine(line string, err error, writer io.Writer) {
+	reason := structs.RejectReasonOf(err)
+	RejectedCounts[reason]++
+
+	RejectedLines = append(RejectedLines, fmt.Sprintf("[%s] %s: %s", reason, err, line))
+	if RejectSampleSize >= 0 && len(RejectedLines) > RejectSampleSize {
+		RejectedLines = RejectedLines[len(RejectedLines)-RejectSampleSize:]
+	}
+
+	if writer != nil {
+		fmt.Fprintln(writer, line)
+	}
+}
+
+// loadRejectedCounts generates the debug rows counting rejected lines by reason
+func loadRejectedCounts() [][]string {
+	rows := make([][]string, 0, len(structs.RejectReasons))
+	for _, reason := range structs.RejectReasons {
+		rows = append(rows, []string{fmt.Sprintf("Rejected (%s)", reason), fmt.Sprintf("%d", RejectedCounts[reason])})
+	}
+	return rows
+}
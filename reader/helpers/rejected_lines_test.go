@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestRecordRejectedLine(t *testing.T) {
+	RejectedCounts = make(map[structs.RejectReason]int)
+	RejectedLines = make([]string, 0)
+	RejectSampleSize = 2
+	defer func() { RejectSampleSize = 100 }()
+
+	parser, _ := structs.LookupParser("clf")
+	var written bytes.Buffer
+	for _, line := range []string{"", "first", "second"} {
+		_, err := parser.Parse(line)
+		RecordRejectedLine(line, err, &written)
+	}
+
+	wantCounts := map[structs.RejectReason]int{structs.RejectEmpty: 1, structs.RejectMismatch: 2}
+	if !reflect.DeepEqual(RejectedCounts, wantCounts) {
+		t.Errorf("RejectedCounts = %v, want %v", RejectedCounts, wantCounts)
+	}
+
+	wantLines := []string{"[Regex Mismatch] Bad regex: first", "[Regex Mismatch] Bad regex: second"}
+	if !reflect.DeepEqual(RejectedLines, wantLines) {
+		t.Errorf("RejectedLines = %v, want %v", RejectedLines, wantLines)
+	}
+
+	if got := written.String(); got != "\nfirst\nsecond\n" {
+		t.Errorf("written = %q", got)
+	}
+}
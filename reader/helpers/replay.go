@@ -0,0 +1,264 @@
+package helpers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// ParseReplaySpeed reads the -speed flag: a multiplier like 1x or 10x, or "max" (returned as 0) for no waiting at all
+func ParseReplaySpeed(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "max" {
+		return 0, nil
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("Bad -speed %q (expected a multiplier like 1x or 10x, or max)", value)
+	}
+	return speed, nil
+}
+
+/*
+Replayer sends the lines of old log files to the UI, waiting between two lines as long as their events were apart
+divided by Speed, so an incident plays out the way it happened (only faster). It can be paused and stepped through
+one line at a time
+*/
+type Replayer struct {
+	// Speed is the replay speed multiplier, 0 replays as fast as the lines can be read
+	Speed float64
+	// Clock tells the time of the latest event ingested, it replaces the wall clock for the trailing windows
+	Clock *structs.EventClock
+
+	mutex  sync.Mutex
+	resume *sync.Cond
+	wake   chan struct{}
+	paused bool
+	steps  int
+}
+
+// ActiveReplay is the replay fed to the UI, it is nil when following live logs
+var ActiveReplay *Replayer
+
+// NewReplayer makes a Replayer replaying at speed
+func NewReplayer(speed float64) *Replayer {
+	replay := &Replayer{
+		Speed: speed,
+		Clock: &structs.EventClock{},
+		wake:  make(chan struct{}, 1),
+	}
+	replay.resume = sync.NewCond(&replay.mutex)
+	return replay
+}
+
+/*
+Start expands patterns into the files to replay (the matches of a glob oldest first, so rotated files come before
+the current one) and starts sending their lines, each file followed by an EOF SourceLine. Files ending in .gz are
+decompressed. newParser is used to read the event times the replay is paced by
+*/
+func (replay *Replayer) Start(patterns []string, newParser structs.ParserFactory) (<-chan structs.SourceLine, error) {
+	locations, err := replayLocations(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(chan structs.SourceLine)
+	go replay.run(locations, newParser, lines)
+	return lines, nil
+}
+
+// replayLocations expands the patterns into the files they match
+func replayLocations(patterns []string) ([]string, error) {
+	locations := make([]string, 0)
+	for _, pattern := range patterns {
+		if pattern == StdinLocation || !hasGlob(pattern) {
+			if _, err := os.Stat(pattern); pattern != StdinLocation && err != nil {
+				return nil, fmt.Errorf("Could not open log file at %s", pattern)
+			}
+			locations = append(locations, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Bad log file pattern %s: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("No log files match %s", pattern)
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return modTime(matches[i]).Before(modTime(matches[j]))
+		})
+		locations = append(locations, matches...)
+	}
+	return locations, nil
+}
+
+// modTime returns when location was last modified
+func modTime(location string) time.Time {
+	info, err := os.Stat(location)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// gzipFile closes the file underneath the gzip.Reader along with it
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close is part of io.Closer
+func (compressed gzipFile) Close() error {
+	compressed.Reader.Close()
+	return compressed.file.Close()
+}
+
+// openReplayFile opens location for reading, decompressing it when it ends in .gz
+func openReplayFile(location string) (io.ReadCloser, error) {
+	if location == StdinLocation {
+		return os.Stdin, nil
+	}
+
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(location, ".gz") {
+		return file, nil
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Could not decompress %s: %v", location, err)
+	}
+	return gzipFile{Reader: reader, file: file}, nil
+}
+
+// run sends the lines of every location in turn, pacing them by the dates of their events
+func (replay *Replayer) run(locations []string, newParser structs.ParserFactory, lines chan<- structs.SourceLine) {
+	var previous time.Time
+	for _, location := range locations {
+		source := location
+		if location == StdinLocation {
+			source = StdinSource
+		}
+
+		reader, err := openReplayFile(location)
+		if err == nil {
+			parser := newParser()
+			// a line that is too long is skipped like it is everywhere else rather than ending the replay of the file
+			scanner := newLineScanner(reader)
+			for scanner.Scan() {
+				text := scanner.Text()
+				event, err := parser.Parse(text)
+				if err != nil || event.Date.IsZero() {
+					// lines without a date go straight through (unless we are paused)
+					event.Date = previous
+				}
+				replay.pace(previous, event.Date)
+				if event.Date.After(previous) {
+					previous = event.Date
+				}
+				lines <- structs.SourceLine{Source: source, Text: text}
+			}
+			reader.Close()
+		}
+		lines <- structs.SourceLine{Source: source, EOF: true}
+	}
+}
+
+/*
+pace blocks while the replay is paused, letting Step through one line at a time, and otherwise waits for the time
+between the previous event and date divided by the speed. Pausing in the middle of a wait holds the rest of it
+*/
+func (replay *Replayer) pace(previous time.Time, date time.Time) {
+	delay := time.Duration(0)
+	if replay.Speed > 0 && !previous.IsZero() && date.After(previous) {
+		delay = time.Duration(float64(date.Sub(previous)) / replay.Speed)
+	}
+
+	for {
+		replay.mutex.Lock()
+		for replay.paused && replay.steps == 0 {
+			replay.resume.Wait()
+		}
+		if replay.paused {
+			// stepping skips the wait
+			replay.steps--
+			replay.mutex.Unlock()
+			return
+		}
+		replay.mutex.Unlock()
+
+		if delay <= 0 {
+			return
+		}
+		started := time.Now()
+		select {
+		case <-time.After(delay):
+			return
+		case <-replay.wake:
+			delay -= time.Since(started)
+		}
+	}
+}
+
+// notify wakes up pace (while the lock is held) when the replay is paused, resumed or stepped
+func (replay *Replayer) notify() {
+	replay.resume.Broadcast()
+	select {
+	case replay.wake <- struct{}{}:
+	default:
+	}
+}
+
+// TogglePause pauses the replay, or resumes it when it is paused
+func (replay *Replayer) TogglePause() {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	replay.paused = !replay.paused
+	replay.steps = 0
+	replay.notify()
+}
+
+// Step lets one more line through while the replay is paused
+func (replay *Replayer) Step() {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	if replay.paused {
+		replay.steps++
+		replay.notify()
+	}
+}
+
+// Paused reports whether the replay is paused
+func (replay *Replayer) Paused() bool {
+	replay.mutex.Lock()
+	defer replay.mutex.Unlock()
+	return replay.paused
+}
+
+// String describes the state of the replay for the debug table
+func (replay *Replayer) String() string {
+	state := "playing"
+	if replay.Paused() {
+		state = "paused (n steps, p resumes)"
+	}
+	if replay.Speed <= 0 {
+		return "max speed, " + state
+	}
+	return fmt.Sprintf("%gx, %s", replay.Speed, state)
+}
@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+func TestParseReplaySpeed(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"1x", 1, false},
+		{"10x", 10, false},
+		{"2.5", 2.5, false},
+		{"max", 0, false},
+		{"MAX", 0, false},
+		{"0x", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseReplaySpeed(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseReplaySpeed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseReplaySpeed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// replayLine is a CLF line for an event seconds after 18:44:00
+func replayLine(seconds int) string {
+	date := time.Date(2019, 3, 23, 18, 44, seconds, 0, time.UTC).Format("02/Jan/2006:15:04:05 -0700")
+	return "127.0.0.1 - james [" + date + "] \"GET /report HTTP/1.0\" 200 123"
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// yesterday's rotated (and compressed) log followed by today's
+	rotated := filepath.Join(dir, "access.log.1.gz")
+	file, err := os.Create(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzip.NewWriter(file)
+	compressed.Write([]byte(replayLine(1) + "\n" + replayLine(2) + "\n"))
+	compressed.Close()
+	file.Close()
+	os.Chtimes(rotated, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	// the long line is skipped and the rest of the file is still replayed
+	current := filepath.Join(dir, "access.log")
+	appendLine(t, current, strings.Repeat("x", maxLineLength+10))
+	appendLine(t, current, replayLine(3))
+	skipped := LongLinesSkipped()
+
+	lines, err := NewReplayer(0).Start([]string{filepath.Join(dir, "access.log*")}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []structs.SourceLine{
+		{Source: rotated, Text: replayLine(1)},
+		{Source: rotated, Text: replayLine(2)},
+		{Source: rotated, EOF: true},
+		{Source: current, Text: replayLine(3)},
+		{Source: current, EOF: true},
+	}
+	for i, wantLine := range want {
+		if got := receiveLine(t, lines); got != wantLine {
+			t.Errorf("line %v = %.80v, want %v", i, got, wantLine)
+		}
+	}
+	if got := LongLinesSkipped() - skipped; got != 1 {
+		t.Errorf("LongLinesSkipped() went up by %d, want 1", got)
+	}
+
+	if _, err := NewReplayer(0).Start([]string{filepath.Join(dir, "*.missing")}, clfParser); err == nil {
+		t.Error("Start() expected an error when nothing matches")
+	}
+}
+
+func TestReplayPaceAndPause(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// two events a second apart take a tenth of a second at 10x
+	location := filepath.Join(dir, "access.log")
+	for _, seconds := range []int{0, 1, 2, 3} {
+		appendLine(t, location, replayLine(seconds))
+	}
+
+	replay := NewReplayer(10)
+	lines, err := replay.Start([]string{location}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveLine(t, lines)
+	started := time.Now()
+	receiveLine(t, lines)
+	if elapsed := time.Since(started); elapsed < 80*time.Millisecond {
+		t.Errorf("second line arrived after %v, want about 100ms", elapsed)
+	}
+
+	// nothing comes through while paused until we step
+	replay.TogglePause()
+	select {
+	case line := <-lines:
+		t.Fatalf("received %v while paused", line)
+	case <-time.After(200 * time.Millisecond):
+	}
+	replay.Step()
+	if got := receiveLine(t, lines); got.Text != replayLine(2) {
+		t.Errorf("stepped line = %v, want %v", got.Text, replayLine(2))
+	}
+
+	replay.TogglePause()
+	if got := receiveLine(t, lines); got.Text != replayLine(3) {
+		t.Errorf("resumed line = %v, want %v", got.Text, replayLine(3))
+	}
+}
@@ -0,0 +1,204 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// syslogSourcePrefix starts the Source of the events received over syslog, it is followed by the sending host
+const syslogSourcePrefix = "syslog:"
+
+/*
+ListenSyslog binds every address, written as udp://host:port, tcp://host:port or host:port for both, and sends
+the payload of every syslog message received to the returned channel tagged with the host that sent it
+*/
+func ListenSyslog(addresses []string) (<-chan structs.SourceLine, error) {
+	lines := make(chan structs.SourceLine)
+	for _, value := range addresses {
+		networks, address := parseSyslogAddress(value)
+		for _, network := range networks {
+			switch network {
+			case "udp":
+				conn, err := net.ListenPacket("udp", address)
+				if err != nil {
+					return nil, fmt.Errorf("Could not listen for syslog on udp %s: %v", address, err)
+				}
+				go receiveSyslogUDP(conn, lines)
+			case "tcp":
+				listener, err := net.Listen("tcp", address)
+				if err != nil {
+					return nil, fmt.Errorf("Could not listen for syslog on tcp %s: %v", address, err)
+				}
+				go acceptSyslogTCP(listener, lines)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// parseSyslogAddress splits the network off a -syslog address, an address without one is listened to on both
+func parseSyslogAddress(value string) ([]string, string) {
+	for _, network := range []string{"udp", "tcp"} {
+		if strings.HasPrefix(value, network+"://") {
+			return []string{network}, strings.TrimPrefix(value, network+"://")
+		}
+	}
+	return []string{"udp", "tcp"}, value
+}
+
+// syslogLine strips the syslog envelope off text, a message without one is passed on whole so it gets rejected
+func syslogLine(text string, remote net.Addr) structs.SourceLine {
+	text = strings.TrimRight(text, "\r\n\x00")
+	host := remote.String()
+	if ip, _, err := net.SplitHostPort(host); err == nil {
+		host = ip
+	}
+
+	message, err := structs.ParseSyslogMessage(text)
+	if err != nil {
+		return structs.SourceLine{Source: syslogSourcePrefix + host, Text: text}
+	}
+	if message.Hostname != "" {
+		host = message.Hostname
+	}
+	return structs.SourceLine{Source: syslogSourcePrefix + host, Text: message.Message}
+}
+
+// receiveSyslogUDP sends the messages of every datagram received (some senders put several in one, a line each)
+func receiveSyslogUDP(conn net.PacketConn, lines chan<- structs.SourceLine) {
+	buffer := make([]byte, 64*1024)
+	for {
+		size, remote, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		for _, text := range strings.Split(string(buffer[:size]), "\n") {
+			if strings.TrimSpace(text) != "" {
+				lines <- syslogLine(text, remote)
+			}
+		}
+	}
+}
+
+// acceptSyslogTCP reads every connection made to listener
+func acceptSyslogTCP(listener net.Listener, lines chan<- structs.SourceLine) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go receiveSyslogTCP(conn, lines)
+	}
+}
+
+/*
+receiveSyslogTCP sends the messages of a connection until it is closed. Messages are either framed by their
+length (RFC 6587 octet counting, like "123 <190>...") or end with a newline, which is also how a line without
+a syslog envelope arrives
+*/
+func receiveSyslogTCP(conn net.Conn, lines chan<- structs.SourceLine) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		counted, err := octetCounted(reader)
+		if err != nil {
+			return
+		}
+
+		var text string
+		if counted {
+			text, err = readOctetCounted(reader)
+		} else {
+			text, err = readSyslogLine(reader)
+		}
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(text) != "" {
+			lines <- syslogLine(text, conn.RemoteAddr())
+		}
+	}
+}
+
+/*
+octetCounted reports whether the next message is octet counted: digits followed by a space and the "<" of the
+priority. Anything else (like an access line starting with an IP address) is read up to its newline. It only
+peeks as far as it needs to tell, so a short message is not held up waiting for more bytes
+*/
+func octetCounted(reader *bufio.Reader) (bool, error) {
+	for size := 1; ; size++ {
+		peeked, err := reader.Peek(size)
+		if err != nil {
+			if len(peeked) > 0 {
+				// the connection ended partway, whatever there is is read as a line
+				return false, nil
+			}
+			return false, err
+		}
+
+		last := peeked[size-1]
+		switch {
+		case size > 1 && peeked[size-2] == ' ':
+			// everything before the space was a digit
+			return last == '<', nil
+		case last >= '0' && last <= '9' && size <= len(strconv.Itoa(maxLineLength)):
+			continue
+		case last == ' ' && size > 1:
+			continue
+		}
+		return false, nil
+	}
+}
+
+// readSyslogLine reads a newline terminated message, one longer than maxLineLength is skipped up to its newline
+func readSyslogLine(reader *bufio.Reader) (string, error) {
+	line := make([]byte, 0)
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > maxLineLength {
+			tooLong = true
+			atomic.AddInt64(&longLinesSkipped, 1)
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err == io.EOF && len(line) > 0 {
+			// the last message of the connection does not need a newline
+			err = nil
+		}
+		if tooLong {
+			return "", err
+		}
+		return string(line), err
+	}
+}
+
+// readOctetCounted reads a "LENGTH MESSAGE" frame
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	prefix, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	length, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	if err != nil || length > maxLineLength {
+		return "", fmt.Errorf("Bad syslog frame length %q", prefix)
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return "", err
+	}
+	return string(message), nil
+}
@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+const syslogAccessLine = `127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /report HTTP/1.0" 200 123`
+
+func TestParseSyslogAddress(t *testing.T) {
+	tests := []struct {
+		value        string
+		wantNetworks []string
+		wantAddress  string
+	}{
+		{"udp://0.0.0.0:514", []string{"udp"}, "0.0.0.0:514"},
+		{"tcp://:1514", []string{"tcp"}, ":1514"},
+		{"127.0.0.1:514", []string{"udp", "tcp"}, "127.0.0.1:514"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			networks, address := parseSyslogAddress(tt.value)
+			if !reflect.DeepEqual(networks, tt.wantNetworks) || address != tt.wantAddress {
+				t.Errorf("parseSyslogAddress() = %v, %v, want %v, %v", networks, address, tt.wantNetworks, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestReceiveSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	lines := make(chan structs.SourceLine)
+	go receiveSyslogUDP(conn, lines)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte("<190>Mar 23 18:44:53 web-1 nginx: " + syslogAccessLine))
+	client.Write([]byte("<190>Mar 23 18:44:53 nginx: " + syslogAccessLine + "\n"))
+
+	want := []structs.SourceLine{
+		{Source: "syslog:web-1", Text: syslogAccessLine},
+		// without a hostname in the message we fall back on the address it came from
+		{Source: "syslog:127.0.0.1", Text: syslogAccessLine},
+	}
+	for i, wantLine := range want {
+		if got := receiveLine(t, lines); got != wantLine {
+			t.Errorf("line %v = %v, want %v", i, got, wantLine)
+		}
+	}
+}
+
+func TestReceiveSyslogTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	lines := make(chan structs.SourceLine)
+	go acceptSyslogTCP(listener, lines)
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// a newline terminated message, an octet counted one, messages without an envelope (one starting with digits
+	// like an octet count), a message too long to keep and one after it
+	framed := "<190>1 2019-03-23T18:44:53Z web-2 nginx - - - " + syslogAccessLine
+	client.Write([]byte("<190>Mar 23 18:44:53 web-1 nginx: " + syslogAccessLine + "\n"))
+	fmt.Fprintf(client, "%d %s", len(framed), framed)
+	client.Write([]byte("garbage\n"))
+	client.Write([]byte(syslogAccessLine + "\n"))
+	client.Write([]byte(strings.Repeat("x", maxLineLength+1) + "\n"))
+	client.Write([]byte("<190>Mar 23 18:44:53 web-3 nginx: " + syslogAccessLine + "\n"))
+
+	want := []structs.SourceLine{
+		{Source: "syslog:web-1", Text: syslogAccessLine},
+		{Source: "syslog:web-2", Text: syslogAccessLine},
+		{Source: "syslog:127.0.0.1", Text: "garbage"},
+		{Source: "syslog:127.0.0.1", Text: syslogAccessLine},
+		{Source: "syslog:web-3", Text: syslogAccessLine},
+	}
+	for i, wantLine := range want {
+		if got := receiveLine(t, lines); got != wantLine {
+			t.Errorf("line %v = %v, want %v", i, got, wantLine)
+		}
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	first := make(chan structs.SourceLine)
+	second := make(chan structs.SourceLine)
+	merged := MergeLines(first, second)
+
+	go func() { first <- structs.SourceLine{Source: "first", Text: "a"} }()
+	if got := receiveLine(t, merged); got.Source != "first" {
+		t.Errorf("MergeLines() sent %v, want the line from first", got)
+	}
+	go func() { second <- structs.SourceLine{Source: "second", Text: "b"} }()
+	if got := receiveLine(t, merged); got.Source != "second" {
+		t.Errorf("MergeLines() sent %v, want the line from second", got)
+	}
+}
@@ -0,0 +1,378 @@
+package helpers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// GlobRescanInterval is how often the log file patterns are globbed again to pick up files created later
+var GlobRescanInterval = 5 * time.Second
+
+// How a file was rotated, the Text of a Rotated SourceLine
+const (
+	RotatedRenamed   = "renamed"
+	RotatedTruncated = "truncated"
+)
+
+// FilePollInterval is how often a followed file that has been read to its end is checked for new lines and rotation
+var FilePollInterval = 250 * time.Millisecond
+
+// fileErrors holds why each file that matches a pattern could not be followed, keyed by location
+var fileErrors = struct {
+	sync.Mutex
+	reasons map[string]string
+}{reasons: make(map[string]string)}
+
+// setFileError records why location could not be followed, or forgets it when err is nil
+func setFileError(location string, err error) {
+	fileErrors.Lock()
+	defer fileErrors.Unlock()
+	if err == nil {
+		delete(fileErrors.reasons, location)
+		return
+	}
+	fileErrors.reasons[location] = err.Error()
+}
+
+// FileErrors returns why the files that could not be followed were not, for the debug table
+func FileErrors() []string {
+	fileErrors.Lock()
+	defer fileErrors.Unlock()
+	reasons := make([]string, 0, len(fileErrors.reasons))
+	for _, reason := range fileErrors.reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// fileTailer follows every file matching a set of patterns and sends their lines to a single channel
+type fileTailer struct {
+	patterns  []string
+	from      StartFrom
+	newParser structs.ParserFactory
+	lines     chan structs.SourceLine
+	mutex     sync.Mutex
+	followed  map[string]bool
+	// gone are the files that were followed until their location went away, they are rotated when they come back
+	gone map[string]bool
+	// pollInterval is FilePollInterval when the tailer was made
+	pollInterval time.Duration
+}
+
+/*
+TailFiles follows every file matching patterns (plain paths or globs like /var/log/nginx/*.access.log) and sends
+each line to the returned channel tagged with the file it came from. Files that start matching later are read
+from the beginning, files that exist now are followed from their end (tail -f) unless from asks for their
+existing content, which is sent first (newParser is used to find timestamps). A plain path has to exist. The
+pattern "-" reads stdin and named pipes are read as they are written to
+*/
+func TailFiles(patterns []string, from StartFrom, newParser structs.ParserFactory) (<-chan structs.SourceLine, error) {
+	tailer := &fileTailer{
+		patterns:  patterns,
+		from:      from,
+		newParser: newParser,
+		lines:     make(chan structs.SourceLine),
+		followed:  make(map[string]bool),
+		gone:      make(map[string]bool),
+
+		pollInterval: FilePollInterval,
+	}
+
+	for _, pattern := range patterns {
+		if pattern == StdinLocation {
+			go readStdin(tailer.lines)
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Bad log file pattern %s: %v", pattern, err)
+		}
+		if !hasGlob(pattern) {
+			if _, err := os.Stat(pattern); err != nil {
+				return nil, fmt.Errorf("Could not open log file at %s", pattern)
+			}
+		}
+	}
+
+	if err := tailer.scan(true); err != nil {
+		return nil, err
+	}
+	go tailer.rescan(GlobRescanInterval)
+	return tailer.lines, nil
+}
+
+// hasGlob reports whether pattern contains any of the characters filepath.Match treats specially
+func hasGlob(pattern string) bool {
+	for _, character := range pattern {
+		switch character {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
+
+// rescan globs the patterns every interval to pick up new files and retry the ones that could not be followed
+func (tailer *fileTailer) rescan(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		// the files that failed are in FileErrors
+		tailer.scan(false)
+	}
+}
+
+/*
+scan starts following every matching file that is not followed yet, initial is set for the files that exist at
+startup. A file that cannot be followed does not stop the others, its error is kept in FileErrors and it is tried
+again on the next scan. The errors of the pass are returned together
+*/
+func (tailer *fileTailer) scan(initial bool) error {
+	tailer.mutex.Lock()
+	defer tailer.mutex.Unlock()
+
+	failures := make([]string, 0)
+	for _, pattern := range tailer.patterns {
+		if pattern == StdinLocation {
+			continue
+		}
+		// the patterns were checked in TailFiles so this cannot fail
+		matches, _ := filepath.Glob(pattern)
+		for _, location := range matches {
+			if tailer.followed[location] {
+				continue
+			}
+			err := tailer.start(location, initial)
+			setFileError(location, err)
+			if err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+			tailer.followed[location] = true
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+/*
+start follows location. A file created later is read from its beginning, one that existed at startup carries on
+from its checkpoint when there is one that still applies and otherwise from where from says
+*/
+func (tailer *fileTailer) start(location string, initial bool) error {
+	if isFIFO(location) {
+		go readFIFO(location, tailer.lines)
+		return nil
+	}
+	if !initial {
+		return tailer.follow(location, 0)
+	}
+	if offset, ok := FileCheckpoints.resumeOffset(location); ok {
+		return tailer.follow(location, offset)
+	}
+	if tailer.from.end() {
+		// seek to the size we see now rather than the end, so the offset we track lines up with the file
+		info, err := os.Stat(location)
+		if err != nil {
+			return fmt.Errorf("Could not open log file at %s", location)
+		}
+		return tailer.follow(location, info.Size())
+	}
+
+	offset, err := backlogOffset(location, tailer.from, tailer.newParser)
+	if err != nil {
+		return fmt.Errorf("Could not read log file at %s: %v", location, err)
+	}
+	return tailer.follow(location, offset)
+}
+
+/*
+follow opens location and reads it from offset in a goroutine, sending its lines to the shared channel. The file is
+opened again when it is renamed or deleted and recreated (logrotate's create) or read from its start when it is
+truncated (copytruncate), and a Rotated SourceLine is sent when that happens. It is called with the mutex held
+*/
+func (tailer *fileTailer) follow(location string, offset int64) error {
+	file, err := os.Open(location)
+	if err != nil {
+		return fmt.Errorf("Could not open log file at %s", location)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("Could not open log file at %s", location)
+	}
+	// a file that went away and is back has been rotated with a gap before the new one was created
+	rotated := tailer.gone[location]
+	delete(tailer.gone, location)
+	go tailer.read(location, file, offset, rotated)
+	return nil
+}
+
+/*
+read sends the lines of file, opened on location at offset, until location goes away (rotated is set when it is
+back after having done so). Every line carries the inode of the file it was read from and the offset of the line
+after it, which are checkpointed once the line has been ingested, so a checkpoint never pairs an offset with a file
+it does not belong to or skips a line still on its way. Once the end of the file is reached it is checked every
+pollInterval for new lines, for having been truncated and for location now being another file, which is switched to
+once what is left of the old one has been read
+*/
+func (tailer *fileTailer) read(location string, file *os.File, offset int64, rotated bool) {
+	lines := newFileLines(file, offset)
+	inode := openedIdentity(file)
+	if rotated {
+		// lines of the file that went away may still be on their way, the notice is checkpointed after them
+		tailer.lines <- structs.SourceLine{Source: location, Text: RotatedRenamed, Rotated: true, Inode: inode}
+	} else {
+		FileCheckpoints.set(location, Checkpoint{Inode: inode, Offset: offset})
+	}
+	for {
+		text, err := lines.next()
+		if err == nil {
+			tailer.lines <- structs.SourceLine{Source: location, Text: text, Inode: inode, Offset: lines.offset}
+			continue
+		}
+
+		time.Sleep(tailer.pollInterval)
+		opened, err := file.Stat()
+		if err != nil {
+			continue
+		}
+		if opened.Size() < lines.offset+lines.read {
+			file.Seek(0, io.SeekStart)
+			lines.reset(file, 0)
+			tailer.lines <- structs.SourceLine{Source: location, Text: RotatedTruncated, Rotated: true, Inode: inode}
+			continue
+		}
+		current, err := os.Stat(location)
+		if os.IsNotExist(err) {
+			// nothing is going to be written to a file no one can open anymore, the rescan picks location up if it is back
+			tailer.drain(location, lines, inode)
+			file.Close()
+			tailer.forget(location)
+			return
+		}
+		if err != nil || os.SameFile(opened, current) {
+			continue
+		}
+		next, err := os.Open(location)
+		if err != nil {
+			continue
+		}
+		tailer.drain(location, lines, inode)
+		file.Close()
+		file, inode = next, openedIdentity(next)
+		lines.reset(file, 0)
+		tailer.lines <- structs.SourceLine{Source: location, Text: RotatedRenamed, Rotated: true, Inode: inode}
+	}
+}
+
+/*
+drain sends the rest of a file that location no longer points to, the lines written just before it was renamed or
+deleted. Nothing is written to it anymore, so a last line without a newline is sent as it is
+*/
+func (tailer *fileTailer) drain(location string, lines *fileLines, inode uint64) {
+	for {
+		text, err := lines.next()
+		if err != nil {
+			break
+		}
+		tailer.lines <- structs.SourceLine{Source: location, Text: text, Inode: inode, Offset: lines.offset}
+	}
+	if text, ok := lines.rest(); ok {
+		tailer.lines <- structs.SourceLine{Source: location, Text: text, Inode: inode, Offset: lines.offset}
+	}
+}
+
+// forget stops counting location as followed once it has gone away, so the rescan follows it again when it is back
+func (tailer *fileTailer) forget(location string) {
+	tailer.mutex.Lock()
+	defer tailer.mutex.Unlock()
+	delete(tailer.followed, location)
+	tailer.gone[location] = true
+}
+
+// openedIdentity returns the inode of the open file, 0 when it is not known
+func openedIdentity(file *os.File) uint64 {
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+	inode, _ := fileIdentity(info)
+	return inode
+}
+
+/*
+fileLines reads the lines of a file that is still being written to. A line whose newline has not been written yet is
+held on to until it has, and a line longer than maxLineLength is skipped and counted in longLinesSkipped rather than
+held in memory
+*/
+type fileLines struct {
+	reader *bufio.Reader
+	// offset is where the line being read starts
+	offset int64
+	// line is what has been read of that line (nothing once it is too long) and read the number of bytes of it
+	line    []byte
+	read    int64
+	tooLong bool
+}
+
+// newFileLines reads the lines of file from offset, which the file has to be at already
+func newFileLines(file *os.File, offset int64) *fileLines {
+	return &fileLines{reader: bufio.NewReader(file), offset: offset}
+}
+
+// reset carries on reading file from offset, which the file has to be at already, dropping the line being read
+func (lines *fileLines) reset(file *os.File, offset int64) {
+	lines.reader.Reset(file)
+	lines.offset = offset
+	lines.line, lines.read, lines.tooLong = lines.line[:0], 0, false
+}
+
+// next returns the next line (without the "\n" or "\r\n"), or io.EOF when the rest of the file is not a whole line
+func (lines *fileLines) next() (string, error) {
+	for {
+		chunk, err := lines.reader.ReadSlice('\n')
+		lines.read += int64(len(chunk))
+		if !lines.tooLong && len(lines.line)+len(chunk) > maxLineLength {
+			lines.tooLong = true
+			lines.line = lines.line[:0]
+			atomic.AddInt64(&longLinesSkipped, 1)
+		}
+		if !lines.tooLong {
+			lines.line = append(lines.line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		text, tooLong := strings.TrimRight(string(lines.line), "\r\n"), lines.tooLong
+		lines.offset += lines.read
+		lines.line, lines.read, lines.tooLong = lines.line[:0], 0, false
+		if !tooLong {
+			return text, nil
+		}
+	}
+}
+
+// rest returns the last line of a file that will not be written to anymore even though it has no newline
+func (lines *fileLines) rest() (string, bool) {
+	text, ok := strings.TrimRight(string(lines.line), "\r\n"), lines.read > 0 && !lines.tooLong
+	lines.offset += lines.read
+	lines.line, lines.read, lines.tooLong = lines.line[:0], 0, false
+	return text, ok
+}
@@ -0,0 +1,311 @@
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veverkap/logtop/reader/structs"
+)
+
+// clfParser makes the parsers of the lines followed in these tests
+var clfParser structs.ParserFactory = func() structs.Parser { return structs.CommonLogParser{} }
+
+func appendLine(t *testing.T, location string, line string) {
+	f, err := os.OpenFile(location, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receiveLine(t *testing.T, lines <-chan structs.SourceLine) structs.SourceLine {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a line")
+	}
+	return structs.SourceLine{}
+}
+
+// receiveFileLine receives a line of a followed file, split from the checkpoint it carries
+func receiveFileLine(t *testing.T, lines <-chan structs.SourceLine) (structs.SourceLine, Checkpoint) {
+	line := receiveLine(t, lines)
+	checkpoint := Checkpoint{Inode: line.Inode, Offset: line.Offset}
+	line.Inode, line.Offset = 0, 0
+	return line, checkpoint
+}
+
+func TestTailFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	GlobRescanInterval = 50 * time.Millisecond
+	defer func() { GlobRescanInterval = 5 * time.Second }()
+
+	first := filepath.Join(dir, "first.access.log")
+	appendLine(t, first, "already there")
+
+	lines, err := TailFiles([]string{filepath.Join(dir, "*.access.log")}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// give the tail a moment to seek to the end before writing
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, first, "new line")
+	got, checkpoint := receiveFileLine(t, lines)
+	if got != (structs.SourceLine{Source: first, Text: "new line"}) {
+		t.Errorf("got %v", got)
+	}
+	// the line carries the checkpoint to record once it has been ingested
+	if want := (Checkpoint{Inode: inodeOf(t, first), Offset: int64(len("already there\nnew line\n"))}); checkpoint != want {
+		t.Errorf("checkpoint = %v, want %v", checkpoint, want)
+	}
+
+	// files created later are read from the beginning, one that cannot be opened does not stop the others
+	broken := filepath.Join(dir, "broken.access.log")
+	if err := os.Symlink(filepath.Join(dir, "missing"), broken); err != nil {
+		t.Fatal(err)
+	}
+	second := filepath.Join(dir, "second.access.log")
+	appendLine(t, second, "from the second file")
+	if got, _ := receiveFileLine(t, lines); got != (structs.SourceLine{Source: second, Text: "from the second file"}) {
+		t.Errorf("got %v", got)
+	}
+	if got, want := FileErrors(), []string{"Could not open log file at " + broken}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FileErrors() = %v, want %v", got, want)
+	}
+
+	// it is followed once it can be
+	appendLine(t, filepath.Join(dir, "missing"), "through the link")
+	if got, _ := receiveFileLine(t, lines); got != (structs.SourceLine{Source: broken, Text: "through the link"}) {
+		t.Errorf("got %v", got)
+	}
+	if got := FileErrors(); len(got) != 0 {
+		t.Errorf("FileErrors() = %v, want none", got)
+	}
+}
+
+func TestTailFilesMissingFile(t *testing.T) {
+	if _, err := TailFiles([]string{"/does/not/exist.log"}, StartFrom{}, clfParser); err == nil {
+		t.Error("TailFiles() expected an error for a missing file")
+	}
+	if _, err := TailFiles([]string{"/tmp/[.log"}, StartFrom{}, clfParser); err == nil {
+		t.Error("TailFiles() expected an error for a bad pattern")
+	}
+}
+
+// rotateByRename moves the log away and starts a new one like logrotate's create
+func rotateByRename(t *testing.T, location string) {
+	if err := os.Rename(location, location+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLine(t, location, "after rotation")
+}
+
+// rotateByCopyTruncate copies the log away and empties it like logrotate's copytruncate
+func rotateByCopyTruncate(t *testing.T, location string) {
+	content, err := ioutil.ReadFile(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(location+".1", content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(location, 0); err != nil {
+		t.Fatal(err)
+	}
+	// give the tail a moment to notice the truncation before the file grows again
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, location, "after rotation")
+}
+
+func TestTailFilesRotation(t *testing.T) {
+	// the rescan picks the file up again when a poll falls between the rename and the new file being created
+	FilePollInterval = 20 * time.Millisecond
+	GlobRescanInterval = 50 * time.Millisecond
+	defer func() {
+		FilePollInterval = 250 * time.Millisecond
+		GlobRescanInterval = 5 * time.Second
+	}()
+
+	tests := []struct {
+		name   string
+		rotate func(t *testing.T, location string)
+		how    string
+	}{
+		{"rename", rotateByRename, RotatedRenamed},
+		{"copytruncate", rotateByCopyTruncate, RotatedTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logtop")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			location := filepath.Join(dir, "access.log")
+			appendLine(t, location, "a line that is longer than the one written after the rotation")
+			lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			appendLine(t, location, "before rotation")
+			if got := receiveLine(t, lines); got.Text != "before rotation" {
+				t.Fatalf("got %v", got)
+			}
+
+			tt.rotate(t, location)
+
+			// the rotation notice comes before the first line of the new file, it checkpoints the start of the file
+			inode := inodeOf(t, location)
+			want := []struct {
+				line       structs.SourceLine
+				checkpoint Checkpoint
+			}{
+				{structs.SourceLine{Source: location, Text: tt.how, Rotated: true}, Checkpoint{Inode: inode}},
+				{
+					structs.SourceLine{Source: location, Text: "after rotation"},
+					Checkpoint{Inode: inode, Offset: int64(len("after rotation\n"))},
+				},
+			}
+			for _, w := range want {
+				got, checkpoint := receiveFileLine(t, lines)
+				if got != w.line || checkpoint != w.checkpoint {
+					t.Fatalf("got %v at %v, want %v at %v", got, checkpoint, w.line, w.checkpoint)
+				}
+			}
+		})
+	}
+}
+
+func TestTailFilesRenameReadsTheRest(t *testing.T) {
+	// the rescan picks the file up again when a poll falls between the rename and the new file being created
+	FilePollInterval = 20 * time.Millisecond
+	GlobRescanInterval = 50 * time.Millisecond
+	defer func() {
+		FilePollInterval = 250 * time.Millisecond
+		GlobRescanInterval = 5 * time.Second
+	}()
+
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "already there")
+	lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the web server writes to the renamed file until it opens the new one
+	if err := os.Rename(location, location+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLine(t, location+".1", "written after the rename")
+	appendLine(t, location, "after rotation")
+
+	want := []structs.SourceLine{
+		{Source: location, Text: "written after the rename"},
+		{Source: location, Text: RotatedRenamed, Rotated: true},
+		{Source: location, Text: "after rotation"},
+	}
+	for _, line := range want {
+		if got, _ := receiveFileLine(t, lines); got != line {
+			t.Fatalf("got %v, want %v", got, line)
+		}
+	}
+}
+
+func TestTailFilesLongLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "already there")
+	lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the long line is skipped, the offset still moves past it
+	skipped := LongLinesSkipped()
+	appendLine(t, location, strings.Repeat("x", maxLineLength+10))
+	appendLine(t, location, "after the long line")
+	got, checkpoint := receiveFileLine(t, lines)
+	if got.Text != "after the long line" {
+		t.Fatalf("got %.20q, want the line after the long one", got.Text)
+	}
+	if got := LongLinesSkipped() - skipped; got != 1 {
+		t.Errorf("LongLinesSkipped() went up by %d, want 1", got)
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Checkpoint{Inode: inodeOf(t, location), Offset: info.Size()}); checkpoint != want {
+		t.Errorf("checkpoint = %v, want %v", checkpoint, want)
+	}
+}
+
+func TestTailFilesDeletedAndRecreated(t *testing.T) {
+	FilePollInterval = 20 * time.Millisecond
+	GlobRescanInterval = 50 * time.Millisecond
+	defer func() {
+		FilePollInterval = 250 * time.Millisecond
+		GlobRescanInterval = 5 * time.Second
+	}()
+
+	dir, err := ioutil.TempDir("", "logtop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	location := filepath.Join(dir, "access.log")
+	appendLine(t, location, "already there")
+	lines, err := TailFiles([]string{location}, StartFrom{}, clfParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// the file is let go once it is deleted and followed from its start when it is back
+	if err := os.Remove(location); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, location, "in the new file")
+
+	want := []structs.SourceLine{
+		{Source: location, Text: RotatedRenamed, Rotated: true},
+		{Source: location, Text: "in the new file"},
+	}
+	for _, line := range want {
+		if got, _ := receiveFileLine(t, lines); got != line {
+			t.Fatalf("got %v, want %v", got, line)
+		}
+	}
+}
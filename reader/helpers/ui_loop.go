@@ -2,19 +2,36 @@ package helpers
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	ui "github.com/gizak/termui"
 	"github.com/gizak/termui/widgets"
-	"github.com/hpcloud/tail"
 
 	"github.com/veverkap/logtop/reader/structs"
 )
 
-// LogEvents is a slice of LogEvents (representations of lines from the log)
-var LogEvents = make([]structs.LogEvent, 0)
+// LogEvents holds the LogEvents (representations of lines from the log) of the longest window in use
+var LogEvents = structs.NewEventStore(statisticsWindow)
+
+// statisticsWindow is the number of seconds the statistics and top values tables cover
+const statisticsWindow = 10
+
+// liveLogSize is the number of lines kept in the live log, older ones scroll away for good
+const liveLogSize = 500
+
+// ClosedSources are the sources (like stdin) that have ended
+var ClosedSources = make([]string, 0)
+
+// LastRotation describes the last log file rotation noticed
+var LastRotation string
 
 // UIStartTime is when the ui started
 var UIStartTime time.Time
@@ -25,36 +42,333 @@ func loadDebugValues() [][]string {
 	diff := now.Sub(UIStartTime)
 	seconds := int(diff.Seconds())
 
-	return [][]string{
+	rows := [][]string{
 		[]string{"Program Duration", fmt.Sprintf("%d secs", seconds)},
-		[]string{"Total Event Count", fmt.Sprintf("%d", len(LogEvents))},
+		[]string{"Total Event Count", fmt.Sprintf("%d", LogEvents.Total())},
+		[]string{"Late or Future Events Dropped", fmt.Sprintf("%d", LogEvents.Dropped())},
+		[]string{"Long Lines Skipped", fmt.Sprintf("%d", LongLinesSkipped())},
+		[]string{fmt.Sprintf("Unique hosts/users in last %d secs", statisticsWindow), formatVisitors(LogEvents.Visitors(statisticsWindow))},
+		[]string{fmt.Sprintf("Unique hosts/users in last %d secs", AlertThresholdDuration), formatVisitors(LogEvents.Visitors(int64(AlertThresholdDuration)))},
 
 		[]string{"AlertThresholdDuration", fmt.Sprintf("%d secs", AlertThresholdDuration)},
 		[]string{"AlertThreshold", fmt.Sprintf("%d/sec", AlertThreshold)},
 		[]string{fmt.Sprintf("Events in last %d secs", AlertThresholdDuration), fmt.Sprintf("%d", ThresholdEventCount)},
 		[]string{fmt.Sprintf("Event rate for last %d secs", AlertThresholdDuration), fmt.Sprintf("%.2f/sec", ThresholdRate)},
 		[]string{"Current Alert State", fmt.Sprintf("%s", CurrentErrorState)},
+		[]string{"Closed Sources", strings.Join(ClosedSources, ", ")},
+		[]string{"File Errors", strings.Join(FileErrors(), ", ")},
+		[]string{"Last Rotation", LastRotation},
+		[]string{"State File", FileCheckpoints.Status()},
+	}
+	if ActiveReplay != nil {
+		rows = append(rows,
+			[]string{"Replay", ActiveReplay.String()},
+			[]string{"Event Time", ActiveReplay.Clock.Now().Format(time.RFC3339)},
+		)
 	}
+	return append(rows, loadRejectedCounts()...)
+}
+
+// formatVisitors shows the estimated unique hosts and users, "~" as they are HyperLogLog estimates
+func formatVisitors(visitors structs.Visitors) string {
+	return fmt.Sprintf("~%d / ~%d", visitors.Hosts(), visitors.Users())
 }
 
-// reloadStatistics generates a table of statistics
-func reloadStatistics(events []structs.LogEvent) [][]string {
-	details := structs.GroupBySection(events)
-	details = structs.SortSectionDetailsByHitsDesc(details)
+// reloadStatistics generates a table of statistics from the totals of each section
+func reloadStatistics(sections structs.SectionCounters) [][]string {
+	details := sections.Details()
 
 	rows := [][]string{
-		[]string{"Section", "Hits", "Errors"},
+		[]string{"Section", "Hits", "Errors", "Hosts", "Users", "p50", "p90", "p99", "Max"},
 	}
 	for _, detail := range details {
-		rows = append(rows, []string{detail.Section, strconv.Itoa(detail.Hits), strconv.Itoa(detail.Errors)})
+		row := []string{
+			detail.Section,
+			strconv.Itoa(detail.Hits),
+			strconv.Itoa(detail.Errors),
+			strconv.Itoa(detail.Visitors.Hosts()),
+			strconv.Itoa(detail.Visitors.Users()),
+		}
+		if sketch := detail.Latency; sketch != nil {
+			row = append(row,
+				formatLatency(sketch.Quantile(0.5)),
+				formatLatency(sketch.Quantile(0.9)),
+				formatLatency(sketch.Quantile(0.99)),
+				formatLatency(sketch.Max()),
+			)
+		} else {
+			// this section has no response times in the log
+			row = append(row, "-", "-", "-", "-")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// formatLatency rounds a latency so it fits in a table cell
+func formatLatency(latency time.Duration) string {
+	if latency >= time.Second {
+		return latency.Round(10 * time.Millisecond).String()
+	}
+	if latency >= time.Millisecond {
+		return latency.Round(100 * time.Microsecond).String()
+	}
+	return latency.Round(time.Microsecond).String()
+}
+
+// summaryStatusesLimit is the number of status codes listed in the Top Statuses row of the summary
+const summaryStatusesLimit = 3
+
+/*
+reloadSummary generates a table summing up the traffic as a whole, with a column for the last 10 seconds and one for
+the alert window
+*/
+func reloadSummary(recent structs.TrafficSummary, alertWindow structs.TrafficSummary) [][]string {
+	summaries := []structs.TrafficSummary{recent, alertWindow}
+	seconds := []int{statisticsWindow, AlertThresholdDuration}
+
+	rows := [][]string{
+		[]string{"", fmt.Sprintf("Last %d secs", seconds[0]), fmt.Sprintf("Last %d secs", seconds[1])},
+	}
+	// addRow adds a row with the value of each window
+	addRow := func(name string, value func(summary structs.TrafficSummary, seconds int) string) {
+		row := []string{name}
+		for index, summary := range summaries {
+			row = append(row, value(summary, seconds[index]))
+		}
+		rows = append(rows, row)
+	}
+
+	addRow("Requests/sec", func(summary structs.TrafficSummary, seconds int) string {
+		return fmt.Sprintf("%.2f", summary.RequestRate(seconds))
+	})
+	for class := 2; class <= 5; class++ {
+		class := class
+		addRow(fmt.Sprintf("%dxx", class), func(summary structs.TrafficSummary, seconds int) string {
+			return formatShare(summary.Classes[class], summary.Hits)
+		})
+	}
+	// the verbs of the alert window include the ones of the last 10 seconds
+	for _, verb := range sortedVerbs(alertWindow.Verbs) {
+		verb := verb
+		addRow(verb, func(summary structs.TrafficSummary, seconds int) string {
+			return formatShare(summary.Verbs[verb], summary.Hits)
+		})
+	}
+	addRow("Total Bytes", func(summary structs.TrafficSummary, seconds int) string {
+		return strconv.Itoa(summary.Bytes)
+	})
+	addRow("Average Bytes", func(summary structs.TrafficSummary, seconds int) string {
+		return fmt.Sprintf("%.0f", summary.AverageBytes())
+	})
+	addRow("Bandwidth", func(summary structs.TrafficSummary, seconds int) string {
+		return fmt.Sprintf("%.0f B/sec", summary.Bandwidth(seconds))
+	})
+	addRow("Top Statuses", func(summary structs.TrafficSummary, seconds int) string {
+		statuses := make([]string, 0)
+		for _, status := range summary.TopStatuses(summaryStatusesLimit) {
+			statuses = append(statuses, fmt.Sprintf("%d (%d)", status.Status, status.Hits))
+		}
+		return strings.Join(statuses, ", ")
+	})
+	return rows
+}
+
+// formatShare shows hits along with their share of total
+func formatShare(hits int, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%.0f%%)", hits, 100*float64(hits)/float64(total))
+}
+
+// sortedVerbs returns the verbs, most hits first (ties are ordered alphabetically)
+func sortedVerbs(verbs map[string]int) []string {
+	sorted := make([]string, 0, len(verbs))
+	for verb := range verbs {
+		sorted = append(sorted, verb)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if verbs[sorted[i]] == verbs[sorted[j]] {
+			return sorted[i] < sorted[j]
+		}
+		return verbs[sorted[i]] > verbs[sorted[j]]
+	})
+	return sorted
+}
+
+// topValuesLimit is the number of rows shown in the "Top ..." tables
+const topValuesLimit = 5
+
+// refererOf is the key used for the Top Referers table
+func refererOf(event structs.LogEvent) string {
+	return event.Referer
+}
+
+// userAgentOf is the key used for the Top User Agents table
+func userAgentOf(event structs.LogEvent) string {
+	return event.UserAgent
+}
+
+// agentOf is the key used for the User Agent Classes table, like "bot/Googlebot"
+func agentOf(event structs.LogEvent) string {
+	if event.AgentFamily == "" {
+		return event.AgentClass
+	}
+	return event.AgentClass + "/" + event.AgentFamily
+}
+
+// protocolOf is the key used for the Protocols table
+func protocolOf(event structs.LogEvent) string {
+	return event.Protocol
+}
+
+// sourceOf is the key used for the Sources table
+func sourceOf(event structs.LogEvent) string {
+	return event.Source
+}
+
+/*
+topTables are the "Top ..." tables by header along with the key they count the events by. The EventStore keeps a
+top-K sketch of each for every second as the events arrive
+*/
+var topTables = []struct {
+	header string
+	key    func(structs.LogEvent) string
+}{
+	{"Referer", refererOf},
+	{"User Agent", userAgentOf},
+	{"Agent", agentOf},
+	{"Protocol", protocolOf},
+	{"Source", sourceOf},
+}
+
+// reloadTopValues generates a table of the most common values counted by sketch
+func reloadTopValues(sketch *structs.TopK, header string) [][]string {
+	rows := [][]string{
+		[]string{header, hitsHeader(sketch)},
+	}
+	for _, entry := range sketch.Top(topValuesLimit) {
+		rows = append(rows, []string{entry.Value, strconv.Itoa(entry.Hits)})
+	}
+	return rows
+}
+
+// hitsHeader labels the hits of a top-K table, showing how far over the counts may be once the sketch is full
+func hitsHeader(sketch *structs.TopK) string {
+	if bound := sketch.ErrorBound(); bound > 0 {
+		return fmt.Sprintf("Hits (±%d)", bound)
+	}
+	return "Hits"
+}
+
+// groupedStatisticsLimit is the number of rows of the statistics table when it is grouped by a field other than section
+const groupedStatisticsLimit = 20
+
+// groupByTop is the name the EventStore counts the GroupBy field under
+const groupByTop = "groupBy"
+
+// groupByOf is the key of the statistics table when grouped by a field other than section, "-" when it is empty
+func groupByOf(event structs.LogEvent) string {
+	if value := event.Field(GroupBy); value != "" {
+		return value
+	}
+	return "-"
+}
+
+/*
+reloadGroupedStatistics generates the statistics table grouped by the GroupBy field from sketch. Fields like path
+or host can have a value per request, so they are counted with a top-K sketch of TopKSize counters rather than one
+per value
+*/
+func reloadGroupedStatistics(sketch *structs.TopK) [][]string {
+	rows := [][]string{
+		[]string{GroupBy, hitsHeader(sketch), "Share", "Max Error"},
+	}
+	for _, entry := range sketch.Top(groupedStatisticsLimit) {
+		rows = append(rows, []string{
+			entry.Value,
+			strconv.Itoa(entry.Hits),
+			fmt.Sprintf("%.1f%%", 100*float64(entry.Hits)/float64(sketch.Total())),
+			strconv.Itoa(entry.Error),
+		})
+	}
+	return rows
+}
+
+// groupedBySection reports whether the statistics table shows the sections (with their latencies) rather than a top-K
+func groupedBySection() bool {
+	return strings.EqualFold(GroupBy, "section")
+}
+
+// appendLiveLog adds row to the rows of the live log, dropping the oldest once there are more than liveLogSize
+func appendLiveLog(rows []string, row string) []string {
+	rows = append(rows, row)
+	if len(rows) > liveLogSize {
+		rows = rows[len(rows)-liveLogSize:]
 	}
 	return rows
 }
 
-// LoopUI loads the UI and then goes into loop
-func LoopUI(tail *tail.Tail) {
+/*
+ingestLine turns a line into a LogEvent with the Parser of its source (made by newParser the first time the source
+is seen, as parsers like the W3C one keep state for the log they read) and adds it to LogEvents. Lines that cannot
+be parsed are recorded as rejected. It reports whether an event was added
+*/
+func ingestLine(line structs.SourceLine, parsers map[string]structs.Parser, newParser structs.ParserFactory, rejectWriter io.Writer) bool {
+	parser, ok := parsers[line.Source]
+	if !ok {
+		parser = newParser()
+		parsers[line.Source] = parser
+	}
+
+	event, err := parser.Parse(line.Text)
+	if err != nil {
+		if err != structs.ErrDirective {
+			// keep track of what we could not parse so low numbers can be explained
+			RecordRejectedLine(line.Text, err, rejectWriter)
+		}
+		return false
+	}
+
+	event.Source = line.Source
+	if ActiveReplay != nil {
+		// when replaying, time moves with the events
+		ActiveReplay.Clock.Observe(event.Date)
+	}
+
+	// tag the event as browser/mobile/bot/cli so it can be grouped and alerted on
+	event.ClassifyUserAgent()
+
+	// we were able to parse this line and need to add it to our LogEvents store
+	LogEvents.Add(event)
+	return true
+}
+
+/*
+LoopUI loads the UI and then goes into loop, turning each line into a LogEvent with a Parser made by newParser.
+Lines that cannot be parsed are also written to rejectWriter unless it is nil
+*/
+func LoopUI(lines <-chan structs.SourceLine, newParser structs.ParserFactory, rejectWriter io.Writer) {
 	UIStartTime = time.Now()
 
+	// the ring of LogEvents has to cover the longest window we look at
+	window := statisticsWindow
+	if AlertThresholdDuration > window {
+		window = AlertThresholdDuration
+	}
+	LogEvents = structs.NewEventStore(window)
+	// the tables are counted per second as the events arrive, rather than from the events when they are drawn
+	for _, table := range topTables {
+		LogEvents.TrackTop(table.header, TopKSize, statisticsWindow, table.key)
+	}
+	if !groupedBySection() {
+		LogEvents.TrackTop(groupByTop, TopKSize, statisticsWindow, groupByOf)
+	}
+	if alertFilterRule != nil {
+		LogEvents.TrackMatching(alertFilterRule.Matches)
+	}
+
 	if err := ui.Init(); err != nil {
 		log.Fatalf("failed to initialize termui: %v", err)
 	}
@@ -74,6 +388,13 @@ func LoopUI(tail *tail.Tail) {
 	liveLog.WrapText = true
 	liveLog.SetRect(0, 0, termWidth/2, termHeight/2)
 
+	// the most recent lines we could not parse, shown instead of the liveLog when toggled with "r"
+	rejectedLog := widgets.NewList()
+	rejectedLog.Title = "Rejected Lines (r to toggle)"
+	rejectedLog.Rows = RejectedLines
+	rejectedLog.WrapText = true
+	showRejected := false
+
 	// holder for any alerts
 	alerts := widgets.NewList()
 	alerts.Title = "Alerts"
@@ -81,84 +402,176 @@ func LoopUI(tail *tail.Tail) {
 	alerts.WrapText = true
 	alerts.SetRect(0, 0, 25, 8)
 
+	// the traffic as a whole
+	summary := widgets.NewTable()
+	summary.Title = "Traffic Summary"
+	summary.TextStyle = ui.NewStyle(ui.ColorWhite)
+
 	statistics := widgets.NewTable()
-	statistics.Rows = reloadStatistics(structs.TrailingEvents(LogEvents, 10))
 	statistics.Title = "Statistics (Last 10 Seconds)"
+	if !groupedBySection() {
+		statistics.Title = fmt.Sprintf("Statistics by %s (Last 10 Seconds)", GroupBy)
+	}
 	statistics.TextStyle = ui.NewStyle(ui.ColorWhite)
 	statistics.SetRect(0, 0, 60, 10)
 
+	referers := widgets.NewTable()
+	referers.Title = "Top Referers (Last 10 Seconds)"
+	referers.TextStyle = ui.NewStyle(ui.ColorWhite)
+
+	userAgents := widgets.NewTable()
+	userAgents.Title = "Top User Agents (Last 10 Seconds)"
+	userAgents.TextStyle = ui.NewStyle(ui.ColorWhite)
+
+	agents := widgets.NewTable()
+	agents.Title = "User Agent Classes (Last 10 Seconds)"
+	agents.TextStyle = ui.NewStyle(ui.ColorWhite)
+
+	sources := widgets.NewTable()
+	sources.Title = "Sources (Last 10 Seconds)"
+	sources.TextStyle = ui.NewStyle(ui.ColorWhite)
+
+	protocols := widgets.NewTable()
+	protocols.Title = "Protocols (Last 10 Seconds)"
+	protocols.TextStyle = ui.NewStyle(ui.ColorWhite)
+
 	grid := ui.NewGrid()
 
 	grid.SetRect(0, 0, termWidth, termHeight)
 
-	grid.Set(
-		ui.NewRow(1.0,
-			ui.NewCol(1.0/2,
-				ui.NewRow(1.0/2, alerts),
-				ui.NewRow(1.0/2, statistics),
-			),
-			ui.NewCol(1.0/2,
-				ui.NewRow(1.0/2, debugTable),
-				ui.NewRow(1.0/2, liveLog),
+	// layoutGrid places the widgets on the grid, swapping the liveLog for the rejectedLog when toggled
+	layoutGrid := func() {
+		var logPanel ui.Drawable = liveLog
+		if showRejected {
+			logPanel = rejectedLog
+		}
+
+		grid.Items = nil
+		grid.Set(
+			ui.NewRow(1.0,
+				ui.NewCol(1.0/2,
+					ui.NewRow(1.0/3,
+						ui.NewCol(1.0/2, alerts),
+						ui.NewCol(1.0/2, summary),
+					),
+					ui.NewRow(1.0/3, statistics),
+					ui.NewRow(1.0/3,
+						ui.NewCol(1.0/5, referers),
+						ui.NewCol(1.0/5, userAgents),
+						ui.NewCol(1.0/5, agents),
+						ui.NewCol(1.0/5, protocols),
+						ui.NewCol(1.0/5, sources),
+					),
+				),
+				ui.NewCol(1.0/2,
+					ui.NewRow(1.0/2, debugTable),
+					ui.NewRow(1.0/2, logPanel),
+				),
 			),
-		),
-	)
+		)
+	}
+	layoutGrid()
+
+	// reloadTables recalculates everything shown for the last 10 seconds along with the debug values and displays it
+	reloadTables := func() {
+		if groupedBySection() {
+			statistics.Rows = reloadStatistics(LogEvents.Sections(statisticsWindow))
+		} else {
+			statistics.Rows = reloadGroupedStatistics(LogEvents.Top(groupByTop, statisticsWindow))
+		}
+		summary.Rows = reloadSummary(LogEvents.Summary(statisticsWindow), LogEvents.Summary(int64(AlertThresholdDuration)))
+		referers.Rows = reloadTopValues(LogEvents.Top("Referer", statisticsWindow), "Referer")
+		userAgents.Rows = reloadTopValues(LogEvents.Top("User Agent", statisticsWindow), "User Agent")
+		agents.Rows = reloadTopValues(LogEvents.Top("Agent", statisticsWindow), "Agent")
+		protocols.Rows = reloadTopValues(LogEvents.Top("Protocol", statisticsWindow), "Protocol")
+		sources.Rows = reloadTopValues(LogEvents.Top("Source", statisticsWindow), "Source")
+
+		debugTable.Rows = loadDebugValues()
+		ui.Render(grid)
+	}
 
-	ui.Render(grid)
+	parsers := make(map[string]structs.Parser)
+	reloadTables()
 
 	uiEvents := ui.PollEvents()
 	ticker := time.NewTicker(500 * time.Millisecond).C
 
+	// a SIGINT or SIGTERM (a deploy stopping us) closes the UI like q, so the checkpoints are still saved on the way out
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
 	for {
 		select {
 		case e := <-uiEvents:
 			switch e.ID {
 			case "q", "<C-c>":
 				return
+			case "p":
+				if ActiveReplay != nil {
+					ActiveReplay.TogglePause()
+					reloadTables()
+				}
+			case "n":
+				if ActiveReplay != nil {
+					ActiveReplay.Step()
+				}
+			case "r":
+				showRejected = !showRejected
+				layoutGrid()
+				ui.Clear()
+				ui.Render(grid)
 			case "<Resize>":
 				payload := e.Payload.(ui.Resize)
 				grid.SetRect(0, 0, payload.Width, payload.Height)
 				ui.Clear()
 				ui.Render(grid)
 			}
-		case line, _ := <-tail.Lines:
-			// we receive a message in the tail file chan
-			event, err := structs.ParseLogEvent(line.Text)
-			if err == nil {
-				// we were able to parse this line and need to add it to our LogEvents slice
-				LogEvents = append(LogEvents, event)
+		case <-signals:
+			return
+		case line := <-lines:
+			// the tables are only redrawn on the ticker, so a busy log costs a parse per line rather than a redraw
+			if line.EOF {
+				// the source has ended (stdin was closed), so we keep the UI up showing the final statistics
+				ClosedSources = append(ClosedSources, line.Source)
+				liveLog.Rows = appendLiveLog(liveLog.Rows, fmt.Sprintf("--- end of %s (press q to quit) ---", line.Source))
+				continue
+			}
+			if line.Rotated {
+				// the file was rotated under us and is being read again from its start
+				LastRotation = fmt.Sprintf("%s (%s) at %s", line.Source, line.Text, time.Now().Format("15:04:05"))
+				liveLog.Rows = appendLiveLog(liveLog.Rows, fmt.Sprintf("--- file rotated at %s: %s ---", time.Now().Format("02/Jan/2006:15:04:05 -0700"), line.Source))
+				FileCheckpoints.ingested(line)
+				continue
+			}
 
+			// we receive a message from one of our sources
+			if ingestLine(line, parsers, newParser, rejectWriter) {
 				// add this line to our liveLog
-				liveLog.Rows = append(liveLog.Rows, line.Text)
-				liveLog.ScrollPageDown()
-
-				// let's check if these changes triggered an alert
-				processErrorState(alerts)
-
-				// recalculate statistics for the last 10 seconds
-				statistics.Rows = reloadStatistics(structs.TrailingEvents(LogEvents, 10))
-
-				// load debug values and display
-				debugTable.Rows = loadDebugValues()
-				ui.Render(grid)
+				liveLog.Rows = appendLiveLog(liveLog.Rows, line.Text)
 			}
+			// only now is the line in the statistics, so a checkpoint saved from here on does not skip it
+			FileCheckpoints.ingested(line)
 		case <-ticker:
 			// it's been 500 ms, let's see if we are in alert
 			processErrorState(alerts)
 
-			// recalculate statistics for the last 10 seconds
-			statistics.Rows = reloadStatistics(structs.TrailingEvents(LogEvents, 10))
-
-			// load debug values and display
-			debugTable.Rows = loadDebugValues()
-			ui.Render(grid)
+			// recalculate statistics for the last 10 seconds and display them along with the latest lines
+			liveLog.ScrollBottom()
+			rejectedLog.Rows = RejectedLines
+			rejectedLog.ScrollBottom()
+			reloadTables()
 		}
 	}
 }
 
 // processErrorState calls the structs.Alert.CalculateErrorState and adds an Alert when appropriate
 func processErrorState(alerts *widgets.List) {
-	errorState := CalculateErrorState(LogEvents, AlertThresholdDuration, AlertThreshold)
+	count := LogEvents.Stats(int64(AlertThresholdDuration)).Hits
+	if alertFilterRule != nil {
+		count = LogEvents.Matching(int64(AlertThresholdDuration))
+	}
+	errorState := CalculateErrorStateFromCount(count, AlertThresholdDuration, AlertThreshold)
 
 	switch errorState {
 	case Triggered:
@@ -169,22 +582,30 @@ func processErrorState(alerts *widgets.List) {
 
 }
 
+// alertFilterLabel describes the AlertFilter for the alert messages
+func alertFilterLabel() string {
+	if AlertFilter == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", AlertFilter)
+}
+
 // displayErrorState adds a text notification to the list that we generated an alert
 func displayErrorState(alerts *widgets.List) {
-	t := time.Now()
+	t := structs.Clock()
 	alerts.Rows = append(
 		alerts.Rows,
-		fmt.Sprintf("High traffic generated an alert - hits = %.2f/sec, triggered at %02d/%s/%d:%02d:%02d:%02d +0000", ThresholdRate, t.Day(), t.Month().String()[:3], t.Year(), t.Hour(), t.Minute(), t.Second()),
+		fmt.Sprintf("High traffic generated an alert%s - hits = %.2f/sec, triggered at %02d/%s/%d:%02d:%02d:%02d +0000", alertFilterLabel(), ThresholdRate, t.Day(), t.Month().String()[:3], t.Year(), t.Hour(), t.Minute(), t.Second()),
 	)
 	alerts.ScrollPageDown()
 }
 
 // displayErrorState adds a text notification to the list that we have recovered from our alert
 func hideErrorState(alerts *widgets.List) {
-	t := time.Now()
+	t := structs.Clock()
 	alerts.Rows = append(
 		alerts.Rows,
-		fmt.Sprintf("High traffic alert recovered - hits = %.2f/sec, triggered at %02d/%s/%d:%02d:%02d:%02d +0000", ThresholdRate, t.Day(), t.Month().String()[:3], t.Year(), t.Hour(), t.Minute(), t.Second()),
+		fmt.Sprintf("High traffic alert recovered%s - hits = %.2f/sec, triggered at %02d/%s/%d:%02d:%02d:%02d +0000", alertFilterLabel(), ThresholdRate, t.Day(), t.Month().String()[:3], t.Year(), t.Hour(), t.Minute(), t.Second()),
 	)
 	alerts.ScrollPageDown()
 }
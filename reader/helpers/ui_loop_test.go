@@ -0,0 +1,19 @@
+package helpers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAppendLiveLog(t *testing.T) {
+	rows := make([]string, 0)
+	for i := 0; i < liveLogSize+10; i++ {
+		rows = appendLiveLog(rows, fmt.Sprintf("line %d", i))
+	}
+	if len(rows) != liveLogSize {
+		t.Fatalf("appendLiveLog() kept %d rows, want %d", len(rows), liveLogSize)
+	}
+	if rows[0] != "line 10" || rows[liveLogSize-1] != fmt.Sprintf("line %d", liveLogSize+9) {
+		t.Errorf("appendLiveLog() kept %q to %q, want the most recent lines", rows[0], rows[liveLogSize-1])
+	}
+}
@@ -1,35 +1,191 @@
 package main
 
 import (
+	"io"
 	"log"
 	"os"
-
-	"github.com/hpcloud/tail"
+	"strings"
+	"time"
 
 	"github.com/veverkap/logtop/reader/helpers"
+	"github.com/veverkap/logtop/reader/structs"
 )
 
 func main() {
-	helpers.ParseFlags()
-	tail := loadTail(helpers.LogFileLocation)
-	helpers.LoopUI(tail)
+	// reader replay [flags] [files] plays old logs through the UI instead of following live ones
+	replay := len(os.Args) > 1 && os.Args[1] == "replay"
+	if replay {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	helpers.ParseFlags(replay)
+	location, err := time.LoadLocation(helpers.Timezone)
+	if err != nil {
+		log.Fatalf("Unknown time zone %s", helpers.Timezone)
+	}
+	structs.TimestampLocation = location
+	unit, err := structs.ParseResponseTimeUnit(helpers.ResponseTimeUnit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	structs.ResponseTimeUnit = unit
+	configureSections()
+	if helpers.UserAgentRulesFile != "" {
+		loadUserAgentRules(helpers.UserAgentRulesFile)
+	}
+
+	extraFields := []string{}
+	if helpers.LogFormat != "" {
+		extraFields = registerLogFormat(helpers.LogFormat)
+		helpers.Format = "custom"
+	}
+	checkGroupBy(helpers.GroupBy, extraFields)
+	if err := helpers.LoadAlertFilter(extraFields); err != nil {
+		log.Fatal(err)
+	}
+	if helpers.JSONFields != "" {
+		registerJSONFields(helpers.JSONFields)
+	}
+	newParser, err := structs.LookupParserFactory(helpers.Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the reject file is opened before the UI takes over the terminal, so failing to open it can still be reported
+	var rejectWriter io.Writer
+	if helpers.RejectFile != "" {
+		rejectFile, err := os.OpenFile(helpers.RejectFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Could not open reject file %s", helpers.RejectFile)
+		}
+		defer rejectFile.Close()
+		rejectWriter = rejectFile
+	}
+	if replay {
+		replayFiles(helpers.LogFileLocations, newParser, rejectWriter)
+		return
+	}
+
+	from, err := helpers.ParseStartFrom(helpers.From)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sources := make([]<-chan structs.SourceLine, 0)
+	if helpers.StateFile != "" {
+		if err := helpers.FileCheckpoints.Load(helpers.StateFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(helpers.LogFileLocations) > 0 {
+		lines, err := helpers.TailFiles(helpers.LogFileLocations, from, newParser)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sources = append(sources, lines)
+	}
+	if len(helpers.SyslogAddresses) > 0 {
+		lines, err := helpers.ListenSyslog(helpers.SyslogAddresses)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sources = append(sources, lines)
+	}
+	if helpers.ListenAddress != "" {
+		lines, err := helpers.ListenHTTP(helpers.ListenAddress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sources = append(sources, lines)
+	}
+	if helpers.StateFile != "" {
+		go helpers.FileCheckpoints.SaveEvery(helpers.StateFile, helpers.CheckpointInterval)
+	}
+	helpers.LoopUI(helpers.MergeLines(sources...), newParser, rejectWriter)
+	if helpers.StateFile != "" {
+		// save where we got to on the way out (q, SIGINT or SIGTERM) so a restart carries on from there
+		if err := helpers.FileCheckpoints.Save(helpers.StateFile); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
-// loadTail loads up a pointer to the tail object used to get updates from inotify
-func loadTail(logFileLocation string) *tail.Tail {
-	tail, err := tail.TailFile(
-		logFileLocation,
-		tail.Config{
-			Follow:    true, // Continue looking for new lines (tail -f)
-			MustExist: true, // Fail early if the file does not exist
-			Location: &tail.SeekInfo{ // Seek to this location before tailing
-				Offset: 0,
-				Whence: os.SEEK_END,
-			},
-		},
-	)
-	if err != nil {
-		log.Fatalf("Could not open log file at %s", logFileLocation)
-	}
-	return tail
+/*
+replayFiles plays the log files through the UI at -speed, the trailing windows and the alert following the time
+of the events rather than the wall clock
+*/
+func replayFiles(locations []string, newParser structs.ParserFactory, rejectWriter io.Writer) {
+	speed, err := helpers.ParseReplaySpeed(helpers.Speed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replay := helpers.NewReplayer(speed)
+	lines, err := replay.Start(locations, newParser)
+	if err != nil {
+		log.Fatal(err)
+	}
+	helpers.ActiveReplay = replay
+	structs.Clock = replay.Clock.Now
+	helpers.LoopUI(lines, newParser, rejectWriter)
+}
+
+// configureSections passes the section flags on to structs.SectionFromPath
+func configureSections() {
+	structs.SectionDepth = helpers.SectionDepth
+	structs.NormalizeIDs = helpers.NormalizeIDs
+	for _, value := range helpers.SectionRules {
+		rule, err := structs.ParseSectionRule(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		structs.SectionRules = append(structs.SectionRules, rule)
+	}
+}
+
+// loadUserAgentRules replaces the built in user agent rule table with the one in the file
+func loadUserAgentRules(location string) {
+	file, err := os.Open(location)
+	if err != nil {
+		log.Fatalf("Could not open user agent rules at %s", location)
+	}
+	defer file.Close()
+
+	rules, err := structs.ReadUserAgentRules(file)
+	if err != nil {
+		log.Fatalf("Could not read user agent rules at %s: %v", location, err)
+	}
+	structs.UserAgentRules = rules
+}
+
+/*
+registerLogFormat compiles the custom log format and registers it as the "custom" parser, returning the names of
+its variables that end up in LogEvent.Extra
+*/
+func registerLogFormat(format string) []string {
+	parser, err := structs.NewTemplateParser(format)
+	if err != nil {
+		log.Fatalf("Could not compile log format: %v", err)
+	}
+	structs.RegisterParser("custom", func() structs.Parser { return parser })
+	return parser.ExtraFields()
+}
+
+// checkGroupBy stops on a -groupBy no event has, which would otherwise put every event in a single empty group
+func checkGroupBy(groupBy string, extraFields []string) {
+	if structs.IsEventField(groupBy) {
+		return
+	}
+	for _, field := range extraFields {
+		if field == groupBy {
+			return
+		}
+	}
+	log.Fatalf("Unknown -groupBy field %q (expected one of %s or a variable of -logFormat)", groupBy, strings.Join(structs.EventFields, ", "))
+}
+
+// registerJSONFields replaces the "json" parser with one reading the given field mapping
+func registerJSONFields(fields string) {
+	mapping, err := structs.ParseJSONFieldMapping(fields)
+	if err != nil {
+		log.Fatalf("Could not read JSON fields: %v", err)
+	}
+	structs.RegisterParser("json", func() structs.Parser { return structs.NewJSONParser(mapping) })
 }
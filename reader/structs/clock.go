@@ -0,0 +1,34 @@
+package structs
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Clock tells the time the trailing windows of TrailingEvents (and so the alert) end at. It is the wall clock when
+following live logs and an EventClock when replaying old ones
+*/
+var Clock = time.Now
+
+// EventClock is a clock that tells the time of the latest event it has observed
+type EventClock struct {
+	mutex  sync.Mutex
+	latest time.Time
+}
+
+// Observe moves the clock forward to date (events that are out of order never move it back)
+func (clock *EventClock) Observe(date time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	if date.After(clock.latest) {
+		clock.latest = date
+	}
+}
+
+// Now returns the date of the latest event observed, it can be used as the Clock
+func (clock *EventClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.latest
+}
@@ -0,0 +1,51 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventClock(t *testing.T) {
+	start := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	tests := []struct {
+		name     string
+		observed []time.Time
+		want     time.Time
+	}{
+		{"nothing observed", nil, time.Time{}},
+		{"in order", []time.Time{start, start.Add(time.Second), start.Add(2 * time.Second)}, start.Add(2 * time.Second)},
+		{"out of order", []time.Time{start.Add(5 * time.Second), start, start.Add(time.Second)}, start.Add(5 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &EventClock{}
+			for _, date := range tt.observed {
+				clock.Observe(date)
+			}
+			if got := clock.Now(); !got.Equal(tt.want) {
+				t.Errorf("EventClock.Now() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrailingEventsWithEventClock(t *testing.T) {
+	start := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(start.Add(time.Minute))()
+
+	old := LogEvent{Date: start}
+	recent := LogEvent{Date: start.Add(55 * time.Second)}
+	want := []LogEvent{recent}
+	if got := TrailingEvents([]LogEvent{old, recent}, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("TrailingEvents() = %v, want %v", got, want)
+	}
+}
+
+// withEventClock makes Clock return now, put it back by calling the function returned
+func withEventClock(now time.Time) func() {
+	clock := &EventClock{}
+	clock.Observe(now)
+	Clock = clock.Now
+	return func() { Clock = time.Now }
+}
@@ -0,0 +1,61 @@
+package structs
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*
+combinedLogRegex matches the Common Log Format with the optional quoted referer and user agent appended.
+The tokens are tighter than commonLogRegex because user agents regularly contain brackets and spaces.
+*/
+var combinedLogRegex = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]*)\] "(([^ "]*) (/[^ "]*)[^"]*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?(?: (\d+(?:\.\d+)?))?$`)
+
+func init() {
+	RegisterParser("combined", func() Parser { return CombinedLogParser{} })
+}
+
+// CombinedLogParser parses lines in the Apache/NCSA Combined Log Format (lines in the Common Log Format are accepted as well)
+type CombinedLogParser struct{}
+
+/*
+Parse takes the log string and returns a LogEvent
+
+A log line is of the format:
+127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /config/update HTTP/1.1" 200 491 "http://example.com/" "Mozilla/5.0 (X11; Linux x86_64)"
+
+optionally followed by the response time (see ParseResponseTime)
+*/
+func (CombinedLogParser) Parse(line string) (LogEvent, error) {
+	if line == "" {
+		return LogEvent{}, newParseError(RejectEmpty, "Empty String")
+	}
+
+	line = strings.ReplaceAll(line, "\n", "")
+
+	result := combinedLogRegex.FindStringSubmatch(line)
+
+	// We have 12 capture places, the referer and user agent are empty for a Common Log Format line
+	if len(result) == 12 {
+		if !ValidHost(result[1]) {
+			return LogEvent{}, newParseError(RejectHost, "Bad host")
+		}
+		event, err := commonLogEvent(result[1], result[2], result[3], result[4], result[7], result[8])
+		if err != nil {
+			return LogEvent{}, err
+		}
+		event.Referer = unquoteLogValue(result[9])
+		event.UserAgent = unquoteLogValue(result[10])
+		event.Duration = ParseResponseTime(result[11])
+		return event, nil
+	}
+	return LogEvent{}, newParseError(RejectMismatch, "Bad regex")
+}
+
+// unquoteLogValue removes the escaping from a quoted log value and treats "-" as empty
+func unquoteLogValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,111 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCombinedLogParserParse(t *testing.T) {
+	date, formattedDate := generateTime(time.Now())
+
+	tests := []struct {
+		name    string
+		line    string
+		want    LogEvent
+		wantErr bool
+	}{
+		{
+			name: "Combined form",
+			line: fmt.Sprintf("127.0.0.1 - frank [%s] \"GET /config/update HTTP/1.1\" 200 491 \"http://example.com/start\" \"Mozilla/5.0 (X11; Linux x86_64) [en]\"", formattedDate),
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /config/update HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/config",
+				Path:       "/config/update",
+				StatusCode: 200,
+				ByteSize:   491,
+				Referer:    "http://example.com/start",
+				UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) [en]",
+			},
+		},
+		{
+			name: "Empty referer and escaped user agent",
+			line: fmt.Sprintf("127.0.0.1 - - [%s] \"POST /api/user HTTP/1.1\" 503 - \"-\" \"curl \\\"7.64\\\"\"", formattedDate),
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "-",
+				Date:       date,
+				Verb:       "POST",
+				Request:    "POST /api/user HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/api",
+				Path:       "/api/user",
+				StatusCode: 503,
+				ByteSize:   0,
+				Error:      true,
+				UserAgent:  "curl \"7.64\"",
+			},
+		},
+		{
+			name: "Common form is accepted",
+			line: fmt.Sprintf("127.0.0.1 - frank [%s] \"DELETE /config HTTP/1.0\" 200 491", formattedDate),
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "DELETE",
+				Request:    "DELETE /config HTTP/1.0",
+				Protocol:   "HTTP/1.0",
+				Section:    "/config",
+				Path:       "/config",
+				StatusCode: 200,
+				ByteSize:   491,
+			},
+		},
+		{
+			name: "Combined form with request time",
+			line: fmt.Sprintf("127.0.0.1 - frank [%s] \"GET /report HTTP/1.1\" 200 491 \"-\" \"curl/7.64\" 0.003", formattedDate),
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /report HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				ByteSize:   491,
+				UserAgent:  "curl/7.64",
+				Duration:   3 * time.Millisecond,
+			},
+		},
+		{
+			name:    "Only a referer",
+			line:    fmt.Sprintf("127.0.0.1 - frank [%s] \"DELETE /config HTTP/1.0\" 200 491 \"http://example.com/\"", formattedDate),
+			want:    LogEvent{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CombinedLogParser{}.Parse(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
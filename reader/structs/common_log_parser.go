@@ -0,0 +1,129 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commonLogRegex is the heart of the program - a big regex to match on the log line and capture necessary tokens
+var commonLogRegex = regexp.MustCompile(`^(\S+) - (.*) \[(.*)\] \"((.*) (\/.*) .*)\" (\d{3}) (\d*)(?: (\d+(?:\.\d+)?))?$`)
+
+// commonLogDateLayout is the layout of the date between the square brackets
+const commonLogDateLayout = "02/Jan/2006:15:04:05 -0700"
+
+func init() {
+	RegisterParser("clf", func() Parser { return CommonLogParser{} })
+}
+
+// CommonLogParser parses lines in the NCSA Common Log Format (https://www.w3.org/Daemon/User/Config/Logging.html)
+type CommonLogParser struct{}
+
+/*
+Parse takes the log string and returns a LogEvent
+
+A log line is of the format:
+127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "DELETE /config/update HTTP/1.0" 401 491
+
+optionally followed by the response time (see ParseResponseTime)
+*/
+func (CommonLogParser) Parse(line string) (LogEvent, error) {
+	// if we get a blank line, we return an empty LogEvent and an error
+	if line == "" {
+		return LogEvent{}, newParseError(RejectEmpty, "Empty String")
+	}
+
+	// double check that we don't have any newlines (tail *should* help us with this)
+	line = strings.ReplaceAll(line, "\n", "")
+
+	result := commonLogRegex.FindStringSubmatch(line)
+
+	// We have 10 capture places, so we have to get that many back (the last is empty without a response time)
+	if len(result) == 10 {
+		if !ValidHost(result[1]) {
+			return LogEvent{}, newParseError(RejectHost, "Bad host")
+		}
+		event, err := commonLogEvent(result[1], result[2], result[3], result[4], result[7], result[8])
+		if err != nil {
+			return LogEvent{}, err
+		}
+		event.Duration = ParseResponseTime(result[9])
+		return event, nil
+	}
+	return LogEvent{}, newParseError(RejectMismatch, "Bad regex")
+}
+
+// commonLogEvent builds a LogEvent out of the tokens shared by the common and combined formats
+func commonLogEvent(host, user, dateString, request, statusString, sizeString string) (LogEvent, error) {
+	// the regex lets anything through between the brackets, so this is where a bad date is caught
+	date, err := ParseTimestamp(dateString)
+	if err != nil {
+		return LogEvent{}, err
+	}
+
+	verb, target, protocol, ok := ParseRequestLine(request)
+	if !ok {
+		return LogEvent{}, newParseError(RejectMismatch, "Bad request %q", request)
+	}
+
+	// we consider it an error if it is not informational or success https://developer.mozilla.org/en-US/docs/Web/HTTP/Status
+	status, _ := strconv.Atoi(statusString)
+
+	// convert string to integer ("-" means no bytes were sent)
+	size, _ := strconv.Atoi(sizeString)
+
+	event := LogEvent{
+		Verb:       verb,
+		Host:       host,
+		Address:    ParseAddress(host),
+		User:       user,
+		Date:       date,
+		Request:    request,
+		Protocol:   protocol,
+		StatusCode: status,
+		ByteSize:   size,
+		Error:      status >= 400,
+	}
+	event.setTarget(target)
+	event.Section = SectionFromPath(event.Path)
+	return event, nil
+}
+
+// ResponseTimeUnit is the unit of the response times in the log (-responseTimeUnit), seconds unless set otherwise
+var ResponseTimeUnit = time.Second
+
+// responseTimeUnits are the names ParseResponseTimeUnit understands
+var responseTimeUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+}
+
+/*
+ParseResponseTimeUnit returns the unit named by value: s (nginx $request_time, Apache %T, CloudFront time-taken),
+ms (Apache %{ms}T, IIS time-taken) or us (Apache %D)
+*/
+func ParseResponseTimeUnit(value string) (time.Duration, error) {
+	unit, ok := responseTimeUnits[value]
+	if !ok {
+		return 0, fmt.Errorf("Unknown response time unit %q (expected s, ms or us)", value)
+	}
+	return unit, nil
+}
+
+/*
+ParseResponseTime converts a response time written in the log into a Duration. The number is read in
+ResponseTimeUnit, as its shape cannot tell Apache %T (whole seconds) from %D (whole microseconds)
+*/
+func ParseResponseTime(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(amount * float64(ResponseTimeUnit))
+}
@@ -0,0 +1,242 @@
+package structs
+
+// WindowStats are the totals of the events of a trailing window
+type WindowStats struct {
+	Hits   int
+	Errors int
+	Bytes  int
+}
+
+// eventBucket holds the totals, summary, visitors, section totals and top values of the events of one second
+type eventBucket struct {
+	second   int64
+	stats    WindowStats
+	summary  TrafficSummary
+	visitors Visitors
+	sections SectionCounters
+	// tops has a TopK for every TrackTop, it is let go once the second is older than what they are asked for
+	tops map[string]*TopK
+	// matching counts the events accepted by the TrackMatching function
+	matching int
+}
+
+// trackedTop is a TopK the EventStore keeps for every second, see TrackTop
+type trackedTop struct {
+	name     string
+	capacity int
+	key      func(LogEvent) string
+}
+
+/*
+EventStore adds up the events of the last few seconds in a ring of one second buckets as they arrive, so memory stays
+bounded however long logtop runs and a trailing window is answered from its own buckets rather than by going through
+every event ever seen. The events themselves are not kept. Seconds older than the ring (or the buckets that have been
+reused since) are evicted. The trailing windows end at Clock(). An EventStore is not safe for concurrent use
+*/
+type EventStore struct {
+	buckets []eventBucket
+	total   int
+	dropped int
+	// tops are counted for the last topSeconds seconds
+	tops       []trackedTop
+	topSeconds int64
+	match      func(LogEvent) bool
+}
+
+// NewEventStore makes an EventStore able to answer windows of up to windowSeconds seconds
+func NewEventStore(windowSeconds int) *EventStore {
+	if windowSeconds < 1 {
+		windowSeconds = 1
+	}
+	// one more bucket than the window for the second we are in
+	buckets := make([]eventBucket, windowSeconds+1)
+	for index := range buckets {
+		buckets[index].second = -1
+	}
+	return &EventStore{buckets: buckets}
+}
+
+/*
+TrackTop makes the store count the values key returns for each event (empty ones are skipped) in a TopK of capacity
+counters per second, which Top adds up for windows of up to lastSeconds seconds. Call it before adding events
+*/
+func (store *EventStore) TrackTop(name string, capacity int, lastSeconds int64, key func(LogEvent) string) {
+	store.tops = append(store.tops, trackedTop{name: name, capacity: capacity, key: key})
+	if lastSeconds > store.topSeconds {
+		store.topSeconds = lastSeconds
+	}
+}
+
+// TrackMatching makes the store count the events match accepts, which Matching adds up. Call it before adding events
+func (store *EventStore) TrackMatching(match func(LogEvent) bool) {
+	store.match = match
+}
+
+// bucketFor returns the bucket second lives in (it may still hold an older second)
+func (store *EventStore) bucketFor(second int64) *eventBucket {
+	size := int64(len(store.buckets))
+	return &store.buckets[(second%size+size)%size]
+}
+
+/*
+Add puts the event in the bucket of its second. Events older than what the bucket already holds are dropped, and so are
+events more than a second ahead of Clock() (a skewed clock or a bad date), which would otherwise evict the bucket of a
+second that is still in the window
+*/
+func (store *EventStore) Add(event LogEvent) {
+	second := event.Date.Unix()
+	if second > Clock().Unix()+1 {
+		store.dropped++
+		return
+	}
+	bucket := store.bucketFor(second)
+	if bucket.second > second {
+		store.dropped++
+		return
+	}
+	if bucket.second < second {
+		// the bucket is a whole ring behind, evict what it holds
+		*bucket = eventBucket{second: second, sections: make(SectionCounters)}
+		store.startTops(bucket)
+	}
+
+	bucket.stats.Hits++
+	if event.Error {
+		bucket.stats.Errors++
+	}
+	bucket.stats.Bytes += event.ByteSize
+	bucket.summary.Add(event)
+	bucket.visitors.Add(event)
+	bucket.sections.Add(event)
+	if store.match != nil && store.match(event) {
+		bucket.matching++
+	}
+	// the tops of a second that is already out of their window have been let go
+	for _, top := range store.tops {
+		if sketch := bucket.tops[top.name]; sketch != nil {
+			if value := top.key(event); value != "" {
+				sketch.Add(value)
+			}
+		}
+	}
+	store.total++
+}
+
+/*
+startTops gives the bucket of a new second an empty TopK for every TrackTop and lets go of the ones of the seconds
+that are now older than topSeconds, so only the seconds Top is asked about hold on to their counters
+*/
+func (store *EventStore) startTops(bucket *eventBucket) {
+	if len(store.tops) == 0 {
+		return
+	}
+	bucket.tops = make(map[string]*TopK, len(store.tops))
+	for _, top := range store.tops {
+		bucket.tops[top.name] = NewTopK(top.capacity)
+	}
+	for index := range store.buckets {
+		if store.buckets[index].second < bucket.second-store.topSeconds {
+			store.buckets[index].tops = nil
+		}
+	}
+}
+
+/*
+window calls visit with the buckets of the last lastSeconds seconds (the seconds from Clock() - lastSeconds up to
+Clock()). Windows longer than the store was made for are cut down to what it holds
+*/
+func (store *EventStore) window(lastSeconds int64, visit func(bucket *eventBucket)) {
+	if max := int64(len(store.buckets)) - 1; lastSeconds > max {
+		lastSeconds = max
+	}
+	now := Clock().Unix()
+	for second := now - lastSeconds; second <= now; second++ {
+		if bucket := store.bucketFor(second); bucket.second == second {
+			visit(bucket)
+		}
+	}
+}
+
+// Stats returns the hits, errors and bytes of the last lastSeconds seconds
+func (store *EventStore) Stats(lastSeconds int64) WindowStats {
+	stats := WindowStats{}
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		stats.Hits += bucket.stats.Hits
+		stats.Errors += bucket.stats.Errors
+		stats.Bytes += bucket.stats.Bytes
+	})
+	return stats
+}
+
+// Summary returns the totals of the traffic as a whole over the last lastSeconds seconds
+func (store *EventStore) Summary(lastSeconds int64) TrafficSummary {
+	summary := TrafficSummary{}
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		summary.Merge(bucket.summary)
+	})
+	return summary
+}
+
+// Visitors estimates the unique hosts and users of the last lastSeconds seconds
+func (store *EventStore) Visitors(lastSeconds int64) Visitors {
+	visitors := Visitors{}
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		visitors.Merge(bucket.visitors)
+	})
+	return visitors
+}
+
+/*
+Sections returns the totals of every section over the last lastSeconds seconds. They are added up from the
+section totals each second keeps as its events arrive (and that expire with it), without going through the events
+*/
+func (store *EventStore) Sections(lastSeconds int64) SectionCounters {
+	sections := make(SectionCounters)
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		sections.Merge(bucket.sections)
+	})
+	return sections
+}
+
+// Matching returns the number of events of the last lastSeconds seconds accepted by the TrackMatching function
+func (store *EventStore) Matching(lastSeconds int64) int {
+	matching := 0
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		matching += bucket.matching
+	})
+	return matching
+}
+
+/*
+Top returns the values counted by the TrackTop called name over the last lastSeconds seconds, merged from the TopK of
+each second (cut down to the seconds TrackTop keeps). It is empty for a name that is not tracked
+*/
+func (store *EventStore) Top(name string, lastSeconds int64) *TopK {
+	capacity := 0
+	for _, top := range store.tops {
+		if top.name == name {
+			capacity = top.capacity
+		}
+	}
+	if lastSeconds > store.topSeconds {
+		lastSeconds = store.topSeconds
+	}
+
+	sketch := NewTopK(capacity)
+	store.window(lastSeconds, func(bucket *eventBucket) {
+		if second, ok := bucket.tops[name]; ok {
+			sketch.Merge(second)
+		}
+	})
+	return sketch
+}
+
+// Total returns the number of events ever added (including the ones evicted since)
+func (store *EventStore) Total() int {
+	return store.total
+}
+
+// Dropped returns the number of events that arrived too late for their bucket or were dated in the future
+func (store *EventStore) Dropped() int {
+	return store.dropped
+}
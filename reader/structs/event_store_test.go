@@ -0,0 +1,193 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventStore(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 500000000, time.UTC)
+	defer withEventClock(now)()
+
+	// an event every second for the last minute, every third one an error
+	store := NewEventStore(10)
+	for seconds := 60; seconds >= 0; seconds-- {
+		store.Add(LogEvent{Date: now.Add(-time.Duration(seconds) * time.Second), ByteSize: 100, Error: seconds%3 == 0})
+	}
+
+	tests := []struct {
+		name        string
+		lastSeconds int64
+		want        WindowStats
+	}{
+		{"now", 0, WindowStats{Hits: 1, Errors: 1, Bytes: 100}},
+		{"last 5 seconds", 5, WindowStats{Hits: 6, Errors: 2, Bytes: 600}},
+		{"last 10 seconds", 10, WindowStats{Hits: 11, Errors: 4, Bytes: 1100}},
+		{"longer than the store holds", 120, WindowStats{Hits: 11, Errors: 4, Bytes: 1100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Stats(tt.lastSeconds); got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := store.Total(); got != 61 {
+		t.Errorf("Total() = %v, want 61", got)
+	}
+
+	// an event older than what its bucket now holds is dropped
+	store.Add(LogEvent{Date: now.Add(-30 * time.Second)})
+	if got := store.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %v, want 1", got)
+	}
+	if got := store.Stats(10).Hits; got != 11 {
+		t.Errorf("Stats(10).Hits = %v after a late event, want 11", got)
+	}
+
+	// an event from the next second is kept, one further ahead is dropped rather than evicting a second in the window
+	store.Add(LogEvent{Date: now.Add(time.Second)})
+	store.Add(LogEvent{Date: now.Add(5 * time.Second)})
+	if got := store.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %v, want 2", got)
+	}
+	if got := store.Stats(5).Hits; got != 6 {
+		t.Errorf("Stats(5).Hits = %v after future events, want 6", got)
+	}
+	if got := store.Total(); got != 62 {
+		t.Errorf("Total() = %v, want 62", got)
+	}
+}
+
+func TestEventStoreMatchesTrailingEvents(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 300000000, time.UTC)
+	defer withEventClock(now)()
+
+	events := make([]LogEvent, 0)
+	store := NewEventStore(10)
+	for milliseconds := 30000; milliseconds >= 0; milliseconds -= 250 {
+		event := LogEvent{Date: now.Add(-time.Duration(milliseconds) * time.Millisecond), Section: fmt.Sprintf("/%d", milliseconds%3)}
+		events = append(events, event)
+		store.Add(event)
+	}
+
+	// both hold on to whole seconds, so at most the events of one second differ at the start of the window
+	trailing := TrailingEvents(events, 10)
+	hits := store.Stats(10).Hits
+	if diff := len(trailing) - hits; diff < 0 || diff > 4 {
+		t.Fatalf("Stats(10) has %v hits, TrailingEvents() %v events", hits, len(trailing))
+	}
+	if !reflect.DeepEqual(store.Sections(10).Details(), GroupBySection(trailing[len(trailing)-hits:])) {
+		t.Error("Sections(10) does not add up to the most recent part of TrailingEvents()")
+	}
+}
+
+func TestEventStoreTop(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	store := NewEventStore(120)
+	store.TrackTop("referer", 10, 10, func(event LogEvent) string { return event.Referer })
+	// google refers every second of the last minute, bing every other one and some events have no referer
+	for seconds := 60; seconds >= 0; seconds-- {
+		date := now.Add(-time.Duration(seconds) * time.Second)
+		store.Add(LogEvent{Date: date, Referer: "https://google.com"})
+		store.Add(LogEvent{Date: date})
+		if seconds%2 == 0 {
+			store.Add(LogEvent{Date: date, Referer: "https://bing.com"})
+		}
+	}
+
+	tests := []struct {
+		name        string
+		lastSeconds int64
+		want        []TopKEntry
+	}{
+		{"now", 0, []TopKEntry{{Value: "https://bing.com", Hits: 1}, {Value: "https://google.com", Hits: 1}}},
+		{"last 5 seconds", 5, []TopKEntry{{Value: "https://google.com", Hits: 6}, {Value: "https://bing.com", Hits: 3}}},
+		{"longer than the tops are kept for", 60, []TopKEntry{{Value: "https://google.com", Hits: 11}, {Value: "https://bing.com", Hits: 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.Top("referer", tt.lastSeconds).Top(0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Top() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := store.Top("untracked", 10).Total(); got != 0 {
+		t.Errorf("Top() of an untracked name has %v hits, want 0", got)
+	}
+}
+
+func TestEventStoreVisitors(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	// three hosts take turns every second of the last minute, one of them logged in
+	store := NewEventStore(120)
+	for seconds := 60; seconds >= 0; seconds-- {
+		host := fmt.Sprintf("10.0.0.%d", seconds%3)
+		user := "-"
+		if seconds%3 == 0 {
+			user = "james"
+		}
+		store.Add(LogEvent{Date: now.Add(-time.Duration(seconds) * time.Second), Host: host, User: user})
+	}
+	// a burst of new hosts in the last second
+	for index := 0; index < 100; index++ {
+		store.Add(LogEvent{Date: now, Host: fmt.Sprintf("192.168.0.%d", index), User: "-"})
+	}
+
+	tests := []struct {
+		name        string
+		lastSeconds int64
+		wantHosts   int
+		wantUsers   int
+	}{
+		{"now", 0, 101, 1},
+		{"last second", 1, 102, 1},
+		{"last 10 seconds", 10, 103, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visitors := store.Visitors(tt.lastSeconds)
+			if got := visitors.Hosts(); got != tt.wantHosts {
+				t.Errorf("Hosts() = %v, want %v", got, tt.wantHosts)
+			}
+			if got := visitors.Users(); got != tt.wantUsers {
+				t.Errorf("Users() = %v, want %v", got, tt.wantUsers)
+			}
+		})
+	}
+
+	if got := store.Sections(10)[""].Visitors.Hosts(); got != 103 {
+		t.Errorf("section Visitors.Hosts() = %v, want 103", got)
+	}
+}
+
+func TestEventStoreMatching(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	// a bot every second of the last minute and a browser every other one
+	store := NewEventStore(120)
+	store.TrackMatching(func(event LogEvent) bool { return event.AgentClass == AgentBot })
+	for seconds := 60; seconds >= 0; seconds-- {
+		date := now.Add(-time.Duration(seconds) * time.Second)
+		store.Add(LogEvent{Date: date, AgentClass: AgentBot})
+		if seconds%2 == 0 {
+			store.Add(LogEvent{Date: date, AgentClass: AgentBrowser})
+		}
+	}
+
+	if got := store.Matching(60); got != 61 {
+		t.Errorf("Matching(60) = %v, want 61", got)
+	}
+	if got := store.Matching(10); got != 11 {
+		t.Errorf("Matching(10) = %v, want 11", got)
+	}
+}
@@ -0,0 +1,53 @@
+package structs
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// hostnameLabelRegex matches a single label of a hostname (RFC 1123)
+var hostnameLabelRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// ParseAddress returns the IPv4 or IPv6 address in host (which may be wrapped in brackets) or nil for a hostname
+func ParseAddress(host string) net.IP {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return net.ParseIP(host)
+}
+
+/*
+ValidHost reports whether host is an IP address or a hostname (as written with HostnameLookups On).
+The last label of a hostname has to contain a letter, so a truncated address like 127.0.0 is rejected.
+A zoned IPv6 address such as fe80::1%eth0 is valid too, though it has no Address
+*/
+func ValidHost(host string) bool {
+	if ParseAddress(host) != nil || zonedAddress(host) {
+		return true
+	}
+	if len(host) == 0 || len(host) > 253 {
+		return false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	for _, label := range labels {
+		if !hostnameLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return strings.IndexAny(strings.ToLower(labels[len(labels)-1]), "abcdefghijklmnopqrstuvwxyz") >= 0
+}
+
+// zonedAddress reports whether host is an IPv6 address with a zone (fe80::1%eth0, maybe in brackets)
+func zonedAddress(host string) bool {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	index := strings.LastIndex(host, "%")
+	if index < 0 || index == len(host)-1 {
+		return false
+	}
+	address := net.ParseIP(host[:index])
+	return address != nil && address.To4() == nil
+}
@@ -0,0 +1,54 @@
+package structs
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		want net.IP
+	}{
+		{host: "127.0.0.1", want: net.ParseIP("127.0.0.1")},
+		{host: "2001:db8::1", want: net.ParseIP("2001:db8::1")},
+		{host: "[::1]", want: net.ParseIP("::1")},
+		{host: "fe80::1%eth0", want: nil},
+		{host: "example.com", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := ParseAddress(tt.host); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "127.0.0.1", want: true},
+		{host: "::ffff:10.0.0.1", want: true},
+		{host: "localhost", want: true},
+		{host: "www.example.com.", want: true},
+		{host: "fe80::1%eth0", want: true},
+		{host: "[fe80::1%25]", want: true},
+		{host: "fe80::1%", want: false},
+		{host: "127.0.0.1%eth0", want: false},
+		{host: "127.0.0", want: false},
+		{host: "-bad-.example.com", want: false},
+		{host: "under_score.com", want: false},
+		{host: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := ValidHost(tt.host); got != tt.want {
+				t.Errorf("ValidHost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
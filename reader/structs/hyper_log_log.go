@@ -0,0 +1,127 @@
+package structs
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hyperLogLogPrecision is the number of hash bits picking a register, 2^12 registers give a 1.6% standard error
+const hyperLogLogPrecision = 12
+
+// hyperLogLogRegisters is the number of registers of a HyperLogLog
+const hyperLogLogRegisters = 1 << hyperLogLogPrecision
+
+// hyperLogLogSparseLimit is the number of registers set before a HyperLogLog switches to a plain array of registers
+const hyperLogLogSparseLimit = hyperLogLogRegisters / 16
+
+/*
+HyperLogLog estimates the number of distinct values added (unique hosts, unique users...) within about 1.6%
+using at most 4KB however many values there are. It starts out sparse, only keeping the registers that are set,
+so the many small HyperLogLogs of a second or of a quiet section stay small
+*/
+type HyperLogLog struct {
+	sparse    map[uint16]uint8
+	registers []uint8
+}
+
+// NewHyperLogLog returns an empty HyperLogLog
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{sparse: make(map[uint16]uint8)}
+}
+
+// hyperLogLogHash hashes value with FNV-1a and mixes the bits (the murmur3 finalizer) as FNV alone spreads similar strings poorly
+func hyperLogLogHash(value string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	hash := hasher.Sum64()
+	hash ^= hash >> 33
+	hash *= 0xff51afd7ed558ccd
+	hash ^= hash >> 33
+	hash *= 0xc4ceb9fe1a85ec53
+	hash ^= hash >> 33
+	return hash
+}
+
+// Add records value
+func (sketch *HyperLogLog) Add(value string) {
+	hash := hyperLogLogHash(value)
+	index := uint16(hash >> (64 - hyperLogLogPrecision))
+	// the rank is the position of the first 1 in the rest of the hash, the marker bit caps it
+	rank := uint8(bits.LeadingZeros64(hash<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1)) + 1)
+	sketch.set(index, rank)
+}
+
+// set raises register index to rank
+func (sketch *HyperLogLog) set(index uint16, rank uint8) {
+	if sketch.registers != nil {
+		if rank > sketch.registers[index] {
+			sketch.registers[index] = rank
+		}
+		return
+	}
+
+	if rank > sketch.sparse[index] {
+		sketch.sparse[index] = rank
+		if len(sketch.sparse) > hyperLogLogSparseLimit {
+			sketch.densify()
+		}
+	}
+}
+
+// densify moves the sparse registers to a plain array of registers
+func (sketch *HyperLogLog) densify() {
+	sketch.registers = make([]uint8, hyperLogLogRegisters)
+	for index, rank := range sketch.sparse {
+		sketch.registers[index] = rank
+	}
+	sketch.sparse = nil
+}
+
+// Merge adds the values of other, the result estimates the number of distinct values of both
+func (sketch *HyperLogLog) Merge(other *HyperLogLog) {
+	if other.registers == nil {
+		for index, rank := range other.sparse {
+			sketch.set(index, rank)
+		}
+		return
+	}
+
+	if sketch.registers == nil {
+		sketch.densify()
+	}
+	for index, rank := range other.registers {
+		if rank > sketch.registers[index] {
+			sketch.registers[index] = rank
+		}
+	}
+}
+
+// Count returns the estimated number of distinct values added
+func (sketch *HyperLogLog) Count() int {
+	registers := float64(hyperLogLogRegisters)
+	sum := 0.0
+	zeros := 0
+	if sketch.registers != nil {
+		for _, rank := range sketch.registers {
+			sum += math.Ldexp(1, -int(rank))
+			if rank == 0 {
+				zeros++
+			}
+		}
+	} else {
+		zeros = hyperLogLogRegisters - len(sketch.sparse)
+		sum = float64(zeros)
+		for _, rank := range sketch.sparse {
+			sum += math.Ldexp(1, -int(rank))
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/registers)
+	estimate := alpha * registers * registers / sum
+	if estimate <= 2.5*registers && zeros > 0 {
+		// linear counting is more accurate while many registers are still empty
+		estimate = registers * math.Log(registers/float64(zeros))
+	}
+	return int(estimate + 0.5)
+}
@@ -0,0 +1,79 @@
+package structs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLogCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+		// allowed relative error, a few times the standard error
+		tolerance float64
+	}{
+		{"empty", 0, 0},
+		{"a handful", 10, 0},
+		{"sparse", 200, 0.02},
+		{"dense", 10000, 0.05},
+		{"many", 200000, 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sketch := NewHyperLogLog()
+			for index := 0; index < tt.distinct; index++ {
+				value := fmt.Sprintf("10.0.%d.%d", index/256, index%256)
+				// duplicates do not count
+				sketch.Add(value)
+				sketch.Add(value)
+			}
+			got := sketch.Count()
+			if math.Abs(float64(got-tt.distinct)) > tt.tolerance*float64(tt.distinct) {
+				t.Errorf("Count() = %v, want %v within %.0f%%", got, tt.distinct, 100*tt.tolerance)
+			}
+		})
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  int
+		second int
+	}{
+		{"sparse into sparse", 50, 50},
+		{"dense into sparse", 50, 5000},
+		{"sparse into dense", 5000, 50},
+		{"dense into dense", 5000, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the two halves overlap by their first 25 values
+			first := NewHyperLogLog()
+			for index := 0; index < tt.first; index++ {
+				first.Add(fmt.Sprintf("first-%d", index))
+			}
+			second := NewHyperLogLog()
+			union := NewHyperLogLog()
+			for index := 0; index < tt.second; index++ {
+				value := fmt.Sprintf("second-%d", index)
+				if index < 25 {
+					value = fmt.Sprintf("first-%d", index)
+				}
+				second.Add(value)
+			}
+			for index := 0; index < tt.first; index++ {
+				union.Add(fmt.Sprintf("first-%d", index))
+			}
+			for index := 25; index < tt.second; index++ {
+				union.Add(fmt.Sprintf("second-%d", index))
+			}
+
+			first.Merge(second)
+			if got, want := first.Count(), union.Count(); got != want {
+				t.Errorf("Merge() counts %v, want %v like adding everything to one", got, want)
+			}
+		})
+	}
+}
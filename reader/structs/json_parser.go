@@ -0,0 +1,252 @@
+package structs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the LogEvent fields a JSONParser can fill
+const (
+	jsonHost      = "host"
+	jsonUser      = "user"
+	jsonDate      = "date"
+	jsonVerb      = "verb"
+	jsonPath      = "path"
+	jsonStatus    = "status"
+	jsonBytes     = "bytes"
+	jsonReferer   = "referer"
+	jsonUserAgent = "user_agent"
+	jsonDuration  = "duration"
+)
+
+// jsonRequiredFields must be present in every line for it to become a LogEvent
+var jsonRequiredFields = []string{jsonDate, jsonPath, jsonStatus}
+
+/*
+JSONFieldMapping maps LogEvent fields (host, user, date, verb, path, status, bytes, referer, user_agent, duration)
+to the keys of a JSON log line. Nested keys are separated by dots, like http.request.method
+*/
+type JSONFieldMapping map[string]string
+
+// DefaultJSONFieldMapping is used when no mapping is given
+var DefaultJSONFieldMapping = JSONFieldMapping{
+	jsonHost:      "host",
+	jsonUser:      "user",
+	jsonDate:      "ts",
+	jsonVerb:      "method",
+	jsonPath:      "path",
+	jsonStatus:    "status",
+	jsonBytes:     "bytes",
+	jsonReferer:   "referer",
+	jsonUserAgent: "user_agent",
+	jsonDuration:  "duration",
+}
+
+func init() {
+	RegisterParser("json", func() Parser { return NewJSONParser(DefaultJSONFieldMapping) })
+}
+
+/*
+ParseJSONFieldMapping reads a comma separated list of field=key pairs like "date=ts,verb=http.request.method"
+on top of DefaultJSONFieldMapping
+*/
+func ParseJSONFieldMapping(value string) (JSONFieldMapping, error) {
+	mapping := JSONFieldMapping{}
+	for field, key := range DefaultJSONFieldMapping {
+		mapping[field] = key
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		pieces := strings.SplitN(pair, "=", 2)
+		if len(pieces) != 2 || pieces[1] == "" {
+			return nil, fmt.Errorf("Bad JSON field mapping %q (expected field=key)", pair)
+		}
+		field := strings.ToLower(strings.TrimSpace(pieces[0]))
+		if _, ok := DefaultJSONFieldMapping[field]; !ok {
+			return nil, fmt.Errorf("Unknown JSON field %q", field)
+		}
+		mapping[field] = strings.TrimSpace(pieces[1])
+	}
+	return mapping, nil
+}
+
+// MissingFieldsError is returned for lines that are valid JSON but lack some of the required fields
+type MissingFieldsError struct {
+	Fields []string
+}
+
+func (err MissingFieldsError) Error() string {
+	return fmt.Sprintf("Missing fields: %s", strings.Join(err.Fields, ", "))
+}
+
+// JSONParser parses JSON-lines access logs using a JSONFieldMapping
+type JSONParser struct {
+	mapping JSONFieldMapping
+}
+
+// NewJSONParser returns a JSONParser reading the keys in mapping
+func NewJSONParser(mapping JSONFieldMapping) *JSONParser {
+	return &JSONParser{mapping: mapping}
+}
+
+/*
+Parse takes the log string and returns a LogEvent
+
+A log line (with the default mapping) is of the format:
+{"ts":"2019-03-23T18:44:53Z","method":"GET","path":"/config/update","status":401,"bytes":491}
+*/
+func (parser *JSONParser) Parse(line string) (LogEvent, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return LogEvent{}, newParseError(RejectEmpty, "Empty String")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return LogEvent{}, newParseError(RejectMismatch, "Bad JSON")
+	}
+	// the decoder stops after the object, anything else on the line means it is not one JSON document
+	if _, err := decoder.Token(); err != io.EOF {
+		return LogEvent{}, newParseError(RejectMismatch, "Trailing data after JSON")
+	}
+
+	// a required field that is null is as good as missing
+	missing := make([]string, 0)
+	for _, field := range jsonRequiredFields {
+		if value, ok := lookupJSONKey(document, parser.mapping[field]); !ok || value == nil {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return LogEvent{}, MissingFieldsError{Fields: missing}
+	}
+
+	event := LogEvent{}
+	fields := make([]string, 0, len(parser.mapping))
+	for field := range parser.mapping {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		value, ok := lookupJSONKey(document, parser.mapping[field])
+		if !ok || value == nil {
+			continue
+		}
+		if err := setJSONField(&event, field, value); err != nil {
+			return LogEvent{}, err
+		}
+	}
+
+	event.Section = SectionFromPath(event.Path)
+	event.Error = event.StatusCode >= 400
+	return event, nil
+}
+
+// lookupJSONKey finds key in document, first as a literal key and then by walking nested objects on each dot
+func lookupJSONKey(document map[string]interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return nil, false
+	}
+	if value, ok := document[key]; ok {
+		return value, true
+	}
+
+	pieces := strings.SplitN(key, ".", 2)
+	if len(pieces) != 2 {
+		return nil, false
+	}
+	nested, ok := document[pieces[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupJSONKey(nested, pieces[1])
+}
+
+// setJSONField sets field of event from a decoded JSON value
+func setJSONField(event *LogEvent, field string, value interface{}) error {
+	switch field {
+	case jsonHost:
+		event.Host = jsonString(value)
+		event.Address = ParseAddress(event.Host)
+	case jsonUser:
+		event.User = jsonString(value)
+	case jsonVerb:
+		event.Verb = jsonString(value)
+	case jsonPath:
+		event.setTarget(jsonString(value))
+	case jsonReferer:
+		event.Referer = jsonString(value)
+	case jsonUserAgent:
+		event.UserAgent = jsonString(value)
+	case jsonDate:
+		date, err := parseJSONTime(value)
+		if err != nil {
+			return err
+		}
+		event.Date = date
+	case jsonStatus:
+		status, err := strconv.Atoi(jsonString(value))
+		if err != nil {
+			return newParseError(RejectStatus, "Bad status %q", jsonString(value))
+		}
+		event.StatusCode = status
+	case jsonBytes:
+		size, _ := strconv.ParseFloat(jsonString(value), 64)
+		event.ByteSize = int(size)
+	case jsonDuration:
+		event.Duration = parseJSONDuration(value)
+	}
+	return nil
+}
+
+// jsonString converts a decoded JSON value to a string
+func jsonString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(bytes.TrimSpace(encoded))
+}
+
+/*
+parseJSONTime accepts everything ParseTimestamp does and epoch timestamps as numbers or strings. Epochs larger than 1e12 are milliseconds, anything else is (fractional) seconds
+*/
+func parseJSONTime(value interface{}) (time.Time, error) {
+	text := jsonString(value)
+	if epoch, err := strconv.ParseFloat(text, 64); err == nil {
+		if math.Abs(epoch) >= 1e12 {
+			return time.Unix(0, int64(epoch)*int64(time.Millisecond)).UTC(), nil
+		}
+		seconds, fraction := math.Modf(epoch)
+		return time.Unix(int64(seconds), int64(fraction*float64(time.Second))).UTC(), nil
+	}
+	return ParseTimestamp(text)
+}
+
+// parseJSONDuration accepts a number of seconds or a Go duration string like "12ms"
+func parseJSONDuration(value interface{}) time.Duration {
+	text := jsonString(value)
+	if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	duration, _ := time.ParseDuration(text)
+	return duration
+}
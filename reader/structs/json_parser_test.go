@@ -0,0 +1,102 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONParserParse(t *testing.T) {
+	date := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	nested, err := ParseJSONFieldMapping("date=time, verb=http.request.method, path=http.request.path, status=http.response.status")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mapping JSONFieldMapping
+		line    string
+		want    LogEvent
+		wantErr error
+	}{
+		{
+			name:    "Default mapping with RFC3339",
+			mapping: DefaultJSONFieldMapping,
+			line:    `{"ts":"2019-03-23T18:44:53Z","method":"GET","path":"/config/update","status":401,"bytes":491,"duration":0.25}`,
+			want: LogEvent{
+				Date:       date,
+				Verb:       "GET",
+				Section:    "/config",
+				Path:       "/config/update",
+				StatusCode: 401,
+				ByteSize:   491,
+				Error:      true,
+				Duration:   250 * time.Millisecond,
+			},
+		},
+		{
+			name:    "Epoch seconds",
+			mapping: DefaultJSONFieldMapping,
+			line:    `{"ts":1553366693,"path":"/report","status":"200"}`,
+			want:    LogEvent{Date: date, Section: "/report", Path: "/report", StatusCode: 200},
+		},
+		{
+			name:    "Epoch milliseconds",
+			mapping: DefaultJSONFieldMapping,
+			line:    `{"ts":1553366693000,"path":"/report","status":200,"duration":"15ms"}`,
+			want:    LogEvent{Date: date, Section: "/report", Path: "/report", StatusCode: 200, Duration: 15 * time.Millisecond},
+		},
+		{
+			name:    "Nested keys",
+			mapping: nested,
+			line:    `{"time":"2019-03-23T18:44:53Z","http":{"request":{"method":"POST","path":"/api/user"},"response":{"status":503}}}`,
+			want:    LogEvent{Date: date, Verb: "POST", Section: "/api", Path: "/api/user", StatusCode: 503, Error: true},
+		},
+		{
+			name:    "Missing required fields",
+			mapping: DefaultJSONFieldMapping,
+			line:    `{"ts":"2019-03-23T18:44:53Z","method":"GET"}`,
+			want:    LogEvent{},
+			wantErr: MissingFieldsError{Fields: []string{"path", "status"}},
+		},
+		{
+			name:    "Null required field",
+			mapping: DefaultJSONFieldMapping,
+			line:    `{"ts":null,"path":"/report","status":200}`,
+			want:    LogEvent{},
+			wantErr: MissingFieldsError{Fields: []string{"date"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewJSONParser(tt.mapping).Parse(tt.line)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONParserParseInvalid(t *testing.T) {
+	parser := NewJSONParser(DefaultJSONFieldMapping)
+	for _, line := range []string{"", "not json", `{"ts":"yesterday","path":"/","status":200}`,
+		`{"ts":1553366693,"path":"/","status":200} trailing`, `{"ts":1553366693,"path":"/","status":200}}`} {
+		if _, err := parser.Parse(line); err == nil {
+			t.Errorf("Parse(%q) expected an error", line)
+		} else if _, ok := err.(MissingFieldsError); ok {
+			t.Errorf("Parse(%q) should not be a MissingFieldsError", line)
+		}
+	}
+}
+
+func TestParseJSONFieldMapping(t *testing.T) {
+	for _, value := range []string{"date", "nope=ts", "date="} {
+		if _, err := ParseJSONFieldMapping(value); err == nil {
+			t.Errorf("ParseJSONFieldMapping(%q) expected an error", value)
+		}
+	}
+}
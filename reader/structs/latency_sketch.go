@@ -0,0 +1,94 @@
+package structs
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// latencySketchAccuracy is the relative error of every quantile returned by a LatencySketch
+const latencySketchAccuracy = 0.01
+
+/*
+LatencySketch is a streaming quantile sketch for durations. Values are counted in logarithmic buckets
+(the DDSketch approach) so every quantile is within 1% of the exact value, while memory only grows with the
+range of durations seen (a few hundred buckets between a microsecond and an hour) rather than with the rate
+*/
+type LatencySketch struct {
+	logGamma float64
+	buckets  map[int]int
+	count    int
+	max      time.Duration
+}
+
+// NewLatencySketch returns an empty LatencySketch
+func NewLatencySketch() *LatencySketch {
+	gamma := (1 + latencySketchAccuracy) / (1 - latencySketchAccuracy)
+	return &LatencySketch{
+		logGamma: math.Log(gamma),
+		buckets:  make(map[int]int),
+	}
+}
+
+// Add records a single duration, durations below a nanosecond are counted as a nanosecond
+func (sketch *LatencySketch) Add(duration time.Duration) {
+	if duration < 1 {
+		duration = 1
+	}
+	index := int(math.Ceil(math.Log(float64(duration)) / sketch.logGamma))
+	sketch.buckets[index]++
+	sketch.count++
+	if duration > sketch.max {
+		sketch.max = duration
+	}
+}
+
+// Merge adds every duration counted by other, which has to have the same accuracy
+func (sketch *LatencySketch) Merge(other *LatencySketch) {
+	for index, count := range other.buckets {
+		sketch.buckets[index] += count
+	}
+	sketch.count += other.count
+	if other.max > sketch.max {
+		sketch.max = other.max
+	}
+}
+
+// Count returns the number of durations added
+func (sketch *LatencySketch) Count() int {
+	return sketch.count
+}
+
+// Max returns the largest duration added (exactly)
+func (sketch *LatencySketch) Max() time.Duration {
+	return sketch.max
+}
+
+// Quantile returns the estimated duration below which the fraction q (0 to 1) of the durations fall
+func (sketch *LatencySketch) Quantile(q float64) time.Duration {
+	if sketch.count == 0 {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(sketch.buckets))
+	for index := range sketch.buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	rank := int(q * float64(sketch.count-1))
+	seen := 0
+	for _, index := range indexes {
+		seen += sketch.buckets[index]
+		if seen > rank {
+			// the middle of the bucket (in relative terms) keeps the error below latencySketchAccuracy
+			gamma := math.Exp(sketch.logGamma)
+			estimate := time.Duration(2 * math.Pow(gamma, float64(index)) / (gamma + 1))
+			if estimate > sketch.max {
+				return sketch.max
+			}
+			return estimate
+		}
+	}
+	return sketch.max
+}
@@ -0,0 +1,43 @@
+package structs
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencySketchQuantile(t *testing.T) {
+	sketch := NewLatencySketch()
+	for i := 1; i <= 1000; i++ {
+		sketch.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		name string
+		q    float64
+		want time.Duration
+	}{
+		{name: "p50", q: 0.5, want: 500 * time.Millisecond},
+		{name: "p90", q: 0.9, want: 900 * time.Millisecond},
+		{name: "p99", q: 0.99, want: 990 * time.Millisecond},
+		{name: "max", q: 1, want: 1000 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sketch.Quantile(tt.q)
+			if math.Abs(float64(got-tt.want)) > float64(tt.want)*latencySketchAccuracy {
+				t.Errorf("Quantile() = %v, want %v (within 1%%)", got, tt.want)
+			}
+		})
+	}
+
+	if sketch.Count() != 1000 || sketch.Max() != time.Second {
+		t.Errorf("Count() = %d, Max() = %v", sketch.Count(), sketch.Max())
+	}
+}
+
+func TestLatencySketchEmpty(t *testing.T) {
+	if got := NewLatencySketch().Quantile(0.5); got != 0 {
+		t.Errorf("Quantile() = %v, want 0", got)
+	}
+}
@@ -1,8 +1,8 @@
 package structs
 
 import (
-	"errors"
-	"regexp"
+	"net"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -10,33 +10,133 @@ import (
 
 // LogEvent represents a line of the log file
 type LogEvent struct {
-	Host       string
-	User       string
-	Date       time.Time
-	Verb       string
-	Section    string
-	Path       string
-	StatusCode int
-	ByteSize   int
-	Error      bool
+	Host        string
+	Address     net.IP
+	User        string
+	Date        time.Time
+	Verb        string
+	Request     string
+	Protocol    string
+	Section     string
+	Path        string
+	RawQuery    string
+	Query       url.Values
+	StatusCode  int
+	ByteSize    int
+	Error       bool
+	Referer     string
+	UserAgent   string
+	AgentClass  string
+	AgentFamily string
+	Duration    time.Duration
+	Extra       map[string]string
+	Source      string
 }
 
-// findSectionDetail finds the index of the matching section (used by GroupBySection)
-func findSectionDetail(details []SectionDetail, section string) int {
-	for i, detail := range details {
-		if detail.Section == section {
-			return i
+// EventFields are the names Field knows besides the Extra ones of custom log formats
+var EventFields = []string{
+	"host", "address", "user", "verb", "section", "path", "protocol", "query", "status", "statuscode", "referer",
+	"useragent", "user_agent", "agentclass", "agent_class", "agentfamily", "agent_family", "source",
+}
+
+// IsEventField reports whether name is one of EventFields, whatever its case
+func IsEventField(name string) bool {
+	for _, field := range EventFields {
+		if strings.EqualFold(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Field returns the named field of the event as a string so statistics and alerts can be keyed on any of them.
+Names that are not one of the LogEvent fields are looked up in Extra (filled by custom log formats)
+*/
+func (event LogEvent) Field(name string) string {
+	switch strings.ToLower(name) {
+	case "host":
+		return event.Host
+	case "address":
+		// hostnames have no address, so they are grouped by name
+		if event.Address == nil {
+			return event.Host
 		}
+		return event.Address.String()
+	case "user":
+		return event.User
+	case "verb":
+		return event.Verb
+	case "section":
+		return event.Section
+	case "path":
+		return event.Path
+	case "protocol":
+		return event.Protocol
+	case "query":
+		return event.RawQuery
+	case "status", "statuscode":
+		return strconv.Itoa(event.StatusCode)
+	case "referer":
+		return event.Referer
+	case "useragent", "user_agent":
+		return event.UserAgent
+	case "agentclass", "agent_class":
+		return event.AgentClass
+	case "agentfamily", "agent_family":
+		return event.AgentFamily
+	case "source":
+		return event.Source
 	}
-	return -1
+	return event.Extra[name]
+}
+
+/*
+ParseRequestLine splits a request line like "GET /api/user?id=1 HTTP/1.1" into the verb, the target and the
+protocol. HTTP/0.9 style lines without a protocol are accepted, ok is false when there is no target
+*/
+func ParseRequestLine(request string) (verb, target, protocol string, ok bool) {
+	pieces := strings.Fields(request)
+	switch len(pieces) {
+	case 2:
+		return pieces[0], pieces[1], "", true
+	case 3:
+		return pieces[0], pieces[1], pieces[2], true
+	}
+	return "", "", "", false
+}
+
+// setTarget splits a request target like /api/user?id=1 into Path, RawQuery and Query
+func (event *LogEvent) setTarget(target string) {
+	rawQuery := ""
+	if index := strings.IndexByte(target, '?'); index >= 0 {
+		target, rawQuery = target[:index], target[index+1:]
+	}
+	event.Path = target
+	event.setRawQuery(rawQuery)
+}
+
+// setRawQuery sets RawQuery (without the leading '?') and the Query parameters parsed from it
+func (event *LogEvent) setRawQuery(rawQuery string) {
+	event.RawQuery = strings.TrimPrefix(rawQuery, "?")
+	event.Query = nil
+	if event.RawQuery != "" {
+		// keep whatever parameters could be parsed out of a malformed query
+		event.Query, _ = url.ParseQuery(event.RawQuery)
+	}
+}
+
+// ClassifyUserAgent fills AgentClass and AgentFamily from UserAgent using UserAgentRules
+func (event *LogEvent) ClassifyUserAgent() {
+	event.AgentClass, event.AgentFamily = ClassifyUserAgent(event.UserAgent)
 }
 
 /*
 TrailingEvents iterates through all of the logEvents appending any that occurred less than
-lastSeconds seconds ago to the filteredEvents and then returns filteredEvents
+lastSeconds seconds before Clock() to the filteredEvents and then returns filteredEvents
 */
 func TrailingEvents(logEvents []LogEvent, lastSeconds int64) []LogEvent {
-	now := time.Now()
+	now := Clock()
 	filteredEvents := make([]LogEvent, 0)
 
 	for _, event := range logEvents {
@@ -48,101 +148,23 @@ func TrailingEvents(logEvents []LogEvent, lastSeconds int64) []LogEvent {
 }
 
 /*
-ParseLogEvent takes the log string and returns a LogEvent
+ParseLogEvent takes the log string and returns a LogEvent using the Common Log Format parser
 
 A log line is of the format:
 127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "DELETE /config/update HTTP/1.0" 401 491
 */
 func ParseLogEvent(line string) (LogEvent, error) {
-	// if we get a blank line, we return an empty LogEvent and an error
-	if line == "" {
-		return LogEvent{}, errors.New("Empty String")
-	}
-
-	// double check that we don't have any newlines (tail *should* help us with this)
-	line = strings.ReplaceAll(line, "\n", "")
-
-	//	The heart of the program - loads up a big regex to match on the log line and capture necessary tokens
-	re, _ := regexp.Compile(`^(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}) - (.*) \[(.*)\] \"((.*) (\/.*) .*)\" (\d{3}) (\d*)$`)
-	result := re.FindStringSubmatch(line)
-
-	// We have 9 capture places, so we have to get that many back
-	if len(result) == 9 {
-		host := result[1]
-		user := result[2]
-
-		// parse the date back from the log file format
-		dateString := result[3]
-		const longForm = "02/Jan/2006:15:04:05 -0700"
-
-		/*
-			We are swallowing this error.  if the log has a date that doesn't match, it *shouldn't* get through the regex,
-			but if it does, we will blow up here
-		*/
-		date, _ := time.Parse(longForm, dateString)
-		verb := result[5]
-
-		// this comes in as something like /path or /section/path so we split and try to get the pieces separately
-		path := result[6]
-		section := path
-		pieces := strings.Split(path, "/")
-		if len(pieces) > 2 {
-			section = "/" + pieces[1]
-		}
-
-		// we consider it an error if it is not informational or success https://developer.mozilla.org/en-US/docs/Web/HTTP/Status
-		status, _ := strconv.Atoi(result[7])
-
-		// convert string to integer
-		size, _ := strconv.Atoi(result[8])
-
-		return LogEvent{
-			Verb:       verb,
-			Host:       host,
-			User:       user,
-			Date:       date,
-			Section:    section,
-			Path:       path,
-			StatusCode: status,
-			ByteSize:   size,
-			Error:      status >= 400,
-		}, nil
-	}
-	return LogEvent{}, errors.New("Bad regex")
+	return CommonLogParser{}.Parse(line)
 }
 
-// GroupBySection iterates through the logEvents generating a slice of SectionDetails grouped by section
+/*
+GroupBySection adds the logEvents up into a SectionDetail per section, ordered by hits (most first) and then by
+section
+*/
 func GroupBySection(logEvents []LogEvent) []SectionDetail {
-	groupedDetails := make([]SectionDetail, 0)
-	for _, v := range logEvents {
-		index := findSectionDetail(groupedDetails, v.Section)
-
-		if index >= 0 {
-			errors := groupedDetails[index].Errors
-			if v.Error {
-				errors++
-			}
-			events := append(groupedDetails[index].Events, v)
-
-			groupedDetails[index] = SectionDetail{
-				Section: v.Section,
-				Events:  events,
-				Hits:    len(events),
-				Errors:  errors,
-			}
-		} else {
-			errors := 0
-			if v.Error {
-				errors++
-			}
-			events := append(make([]LogEvent, 0), v)
-			groupedDetails = append(groupedDetails, SectionDetail{
-				Section: v.Section,
-				Events:  events,
-				Hits:    1,
-				Errors:  errors,
-			})
-		}
+	counters := make(SectionCounters)
+	for _, event := range logEvents {
+		counters.Add(event)
 	}
-	return groupedDetails
+	return counters.Details()
 }
@@ -2,6 +2,8 @@ package structs
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -81,6 +83,8 @@ func TestParseLogEvent(t *testing.T) {
 
 	type args struct {
 		line string
+		// unit is the ResponseTimeUnit of the line, seconds when not set
+		unit time.Duration
 	}
 
 	tests := []struct {
@@ -95,9 +99,12 @@ func TestParseLogEvent(t *testing.T) {
 			},
 			want: LogEvent{
 				Host:       "127.0.0.1",
+				Address:    net.ParseIP("127.0.0.1"),
 				User:       "frank",
 				Date:       date,
 				Verb:       "DELETE",
+				Request:    "DELETE /config/update HTTP/1.0",
+				Protocol:   "HTTP/1.0",
 				Section:    "/config",
 				Path:       "/config/update",
 				StatusCode: 401,
@@ -112,9 +119,12 @@ func TestParseLogEvent(t *testing.T) {
 			},
 			want: LogEvent{
 				Host:       "127.0.0.1",
+				Address:    net.ParseIP("127.0.0.1"),
 				User:       "frank",
 				Date:       date,
 				Verb:       "DELETE",
+				Request:    "DELETE /config HTTP/1.0",
+				Protocol:   "HTTP/1.0",
 				Section:    "/config",
 				Path:       "/config",
 				StatusCode: 200,
@@ -122,6 +132,114 @@ func TestParseLogEvent(t *testing.T) {
 				Error:      false,
 			},
 		},
+		{
+			name: "IPv6 host",
+			args: args{
+				line: fmt.Sprintf("2001:db8::1 - frank [%s] \"GET /api/user HTTP/1.1\" 200 491", formattedDate),
+			},
+			want: LogEvent{
+				Host:       "2001:db8::1",
+				Address:    net.ParseIP("2001:db8::1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /api/user HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/api",
+				Path:       "/api/user",
+				StatusCode: 200,
+				ByteSize:   491,
+				Error:      false,
+			},
+		},
+		{
+			name: "Hostname host",
+			args: args{
+				line: fmt.Sprintf("crawl-66-249-66-1.googlebot.com - - [%s] \"GET /report HTTP/1.1\" 200 491", formattedDate),
+			},
+			want: LogEvent{
+				Host:       "crawl-66-249-66-1.googlebot.com",
+				User:       "-",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /report HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				ByteSize:   491,
+				Error:      false,
+			},
+		},
+		{
+			name: "With nginx request time",
+			args: args{
+				line: fmt.Sprintf("127.0.0.1 - frank [%s] \"GET /report HTTP/1.0\" 200 491 0.125", formattedDate),
+			},
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    net.ParseIP("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /report HTTP/1.0",
+				Protocol:   "HTTP/1.0",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				ByteSize:   491,
+				Duration:   125 * time.Millisecond,
+			},
+		},
+		{
+			name: "With Apache %D",
+			args: args{
+				line: fmt.Sprintf("127.0.0.1 - frank [%s] \"GET /report HTTP/1.0\" 200 491 1500", formattedDate),
+				unit: time.Microsecond,
+			},
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    net.ParseIP("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /report HTTP/1.0",
+				Protocol:   "HTTP/1.0",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				ByteSize:   491,
+				Duration:   1500 * time.Microsecond,
+			},
+		},
+		{
+			name: "Query string",
+			args: args{
+				line: fmt.Sprintf("127.0.0.1 - frank [%s] \"GET /api/user?id=1&id=2&name=x HTTP/2.0\" 200 491", formattedDate),
+			},
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    net.ParseIP("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /api/user?id=1&id=2&name=x HTTP/2.0",
+				Protocol:   "HTTP/2.0",
+				Section:    "/api",
+				Path:       "/api/user",
+				RawQuery:   "id=1&id=2&name=x",
+				Query:      url.Values{"id": []string{"1", "2"}, "name": []string{"x"}},
+				StatusCode: 200,
+				ByteSize:   491,
+			},
+		},
+		{
+			name: "Bad date",
+			args: args{
+				line: "127.0.0.1 - frank [yesterday] \"DELETE /config HTTP/1.0\" 200 491",
+			},
+			want: LogEvent{},
+		},
 		{
 			name: "Bad format on host",
 			args: args{
@@ -132,6 +250,10 @@ func TestParseLogEvent(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.args.unit != 0 {
+				ResponseTimeUnit = tt.args.unit
+				defer func() { ResponseTimeUnit = time.Second }()
+			}
 			if got, _ := ParseLogEvent(tt.args.line); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ParseLogEvent() = %v, want %v", got, tt.want)
 			}
@@ -139,14 +261,47 @@ func TestParseLogEvent(t *testing.T) {
 	}
 }
 
+func TestParseResponseTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		unit  string
+		want  time.Duration
+	}{
+		{"nginx $request_time", "0.125", "s", 125 * time.Millisecond},
+		{"Apache %T", "2", "s", 2 * time.Second},
+		{"Apache %{ms}T", "15", "ms", 15 * time.Millisecond},
+		{"Apache %D", "1500", "us", 1500 * time.Microsecond},
+		{"Missing", "", "s", 0},
+		{"Not a number", "-", "s", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, err := ParseResponseTimeUnit(tt.unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ResponseTimeUnit = unit
+			defer func() { ResponseTimeUnit = time.Second }()
+			if got := ParseResponseTime(tt.value); got != tt.want {
+				t.Errorf("ParseResponseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseResponseTimeUnit("minutes"); err == nil {
+		t.Error("ParseResponseTimeUnit(\"minutes\") expected an error")
+	}
+}
+
 func TestGroupBySection(t *testing.T) {
 	type args struct {
 		vs []LogEvent
 	}
 	now := time.Now()
-	section1 := LogEvent{Section: "/scuba", Path: "/scuba/doo", Error: false, Date: now}
-	section2 := LogEvent{Section: "/scuba", Path: "/scuba/loo", Error: true, Date: now}
-	section3 := LogEvent{Section: "/other", Path: "/other/loo", Error: false, Date: now}
+	section1 := LogEvent{Section: "/scuba", Path: "/scuba/doo", StatusCode: 200, Error: false, Date: now}
+	section2 := LogEvent{Section: "/scuba", Path: "/scuba/loo", StatusCode: 500, Error: true, Date: now}
+	section3 := LogEvent{Section: "/other", Path: "/other/loo", StatusCode: 200, Error: false, Date: now}
 
 	tests := []struct {
 		name string
@@ -160,10 +315,10 @@ func TestGroupBySection(t *testing.T) {
 			},
 			want: []SectionDetail{
 				SectionDetail{
-					Section: "/scuba",
-					Events:  []LogEvent{section1, section2},
-					Hits:    2,
-					Errors:  1,
+					Section:  "/scuba",
+					Statuses: map[int]int{200: 1, 500: 1},
+					Hits:     2,
+					Errors:   1,
 				},
 			},
 		},
@@ -174,21 +329,16 @@ func TestGroupBySection(t *testing.T) {
 			},
 			want: []SectionDetail{
 				SectionDetail{
-					Section: "/scuba",
-					Events: []LogEvent{
-						section1,
-						section2,
-					},
-					Hits:   2,
-					Errors: 1,
+					Section:  "/scuba",
+					Statuses: map[int]int{200: 1, 500: 1},
+					Hits:     2,
+					Errors:   1,
 				},
 				SectionDetail{
-					Section: "/other",
-					Events: []LogEvent{
-						section3,
-					},
-					Hits:   1,
-					Errors: 0,
+					Section:  "/other",
+					Statuses: map[int]int{200: 1},
+					Hits:     1,
+					Errors:   0,
 				},
 			},
 		},
@@ -201,3 +351,26 @@ func TestGroupBySection(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRequestLine(t *testing.T) {
+	tests := []struct {
+		request      string
+		wantVerb     string
+		wantTarget   string
+		wantProtocol string
+		wantOk       bool
+	}{
+		{request: "GET /a?b=c HTTP/1.1", wantVerb: "GET", wantTarget: "/a?b=c", wantProtocol: "HTTP/1.1", wantOk: true},
+		{request: "GET /a", wantVerb: "GET", wantTarget: "/a", wantOk: true},
+		{request: "GET", wantOk: false},
+		{request: "GET /a b HTTP/1.1", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.request, func(t *testing.T) {
+			verb, target, protocol, ok := ParseRequestLine(tt.request)
+			if verb != tt.wantVerb || target != tt.wantTarget || protocol != tt.wantProtocol || ok != tt.wantOk {
+				t.Errorf("ParseRequestLine() = %q, %q, %q, %v", verb, target, protocol, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package structs
+
+import "fmt"
+
+// RejectReason int for "enum" of the reasons a line could not become a LogEvent
+type RejectReason int
+
+// The reasons a line can be rejected, RejectOther covers errors that are not a ParseError
+const (
+	RejectEmpty         RejectReason = iota
+	RejectMismatch      RejectReason = iota
+	RejectDate          RejectReason = iota
+	RejectStatus        RejectReason = iota
+	RejectHost          RejectReason = iota
+	RejectMissingFields RejectReason = iota
+	RejectOther         RejectReason = iota
+)
+
+// RejectReasons lists every RejectReason in order (used to display the counters)
+var RejectReasons = []RejectReason{RejectEmpty, RejectMismatch, RejectDate, RejectStatus, RejectHost, RejectMissingFields, RejectOther}
+
+// String converts the RejectReason to a string representation
+func (reason RejectReason) String() string {
+	names := []string{"Empty", "Regex Mismatch", "Bad Date", "Bad Status", "Bad Host", "Missing Fields", "Other"}
+
+	if reason < RejectEmpty || reason > RejectOther {
+		return "Unknown"
+	}
+	return names[reason]
+}
+
+// ParseError is returned by the parsers when a line is rejected
+type ParseError struct {
+	Reason  RejectReason
+	Message string
+}
+
+func (err ParseError) Error() string {
+	return err.Message
+}
+
+// newParseError formats a ParseError
+func newParseError(reason RejectReason, format string, args ...interface{}) ParseError {
+	return ParseError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// RejectReasonOf classifies an error returned by a Parser
+func RejectReasonOf(err error) RejectReason {
+	switch typed := err.(type) {
+	case ParseError:
+		return typed.Reason
+	case MissingFieldsError:
+		return RejectMissingFields
+	}
+	return RejectOther
+}
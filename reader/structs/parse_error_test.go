@@ -0,0 +1,51 @@
+package structs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRejectReasonOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want RejectReason
+	}{
+		{name: "parse error", err: newParseError(RejectDate, "Bad date %q", "x"), want: RejectDate},
+		{name: "missing fields", err: MissingFieldsError{Fields: []string{"path"}}, want: RejectMissingFields},
+		{name: "anything else", err: errors.New("boom"), want: RejectOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RejectReasonOf(tt.err); got != tt.want {
+				t.Errorf("RejectReasonOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsersRejectReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		want   RejectReason
+	}{
+		{name: "clf empty", format: "clf", line: "", want: RejectEmpty},
+		{name: "clf mismatch", format: "clf", line: "garbage", want: RejectMismatch},
+		{name: "clf host", format: "clf", line: "127.0.0 - frank [23/Mar/2019:18:44:53 +0000] \"GET /config HTTP/1.0\" 200 491", want: RejectHost},
+		{name: "json date", format: "json", line: `{"ts":"yesterday","path":"/","status":200}`, want: RejectDate},
+		{name: "json status", format: "json", line: `{"ts":1553366693,"path":"/","status":"OK"}`, want: RejectStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := LookupParser(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.Parse(tt.line); RejectReasonOf(err) != tt.want {
+				t.Errorf("Parse() error = %v, want reason %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package structs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parser turns a single line of a log file into a LogEvent
+type Parser interface {
+	Parse(line string) (LogEvent, error)
+}
+
+// ParserFunc allows an ordinary function to be used as a Parser
+type ParserFunc func(line string) (LogEvent, error)
+
+// Parse calls f(line)
+func (f ParserFunc) Parse(line string) (LogEvent, error) {
+	return f(line)
+}
+
+/*
+ParserFactory creates a Parser. Parsers that keep state between lines (like the W3C parser reading its
+#Fields directive) need a fresh instance for every log they read, so the registry holds factories
+*/
+type ParserFactory func() Parser
+
+// parsers holds every registered ParserFactory keyed by its format name
+var parsers = make(map[string]ParserFactory)
+
+// RegisterParser makes a Parser available under name (registering the same name twice replaces the first)
+func RegisterParser(name string, factory ParserFactory) {
+	parsers[strings.ToLower(name)] = factory
+}
+
+// LookupParser returns a new Parser registered under name or an error listing the known formats
+func LookupParser(name string) (Parser, error) {
+	factory, err := LookupParserFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(), nil
+}
+
+// LookupParserFactory returns the ParserFactory registered under name so a Parser can be made for every log source
+func LookupParserFactory(name string) (ParserFactory, error) {
+	factory, ok := parsers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown log format %q (known formats: %s)", name, strings.Join(ParserNames(), ", "))
+	}
+	return factory, nil
+}
+
+// ParserNames returns the sorted names of all registered parsers
+func ParserNames() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,86 @@
+package structs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLookupParser(t *testing.T) {
+	custom := ParserFunc(func(line string) (LogEvent, error) {
+		return LogEvent{Path: line}, nil
+	})
+	RegisterParser("Test", func() Parser { return custom })
+	defer delete(parsers, "test")
+
+	tests := []struct {
+		name    string
+		format  string
+		line    string
+		want    LogEvent
+		wantErr bool
+	}{
+		{
+			name:   "clf is registered",
+			format: "clf",
+			line:   "127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] \"DELETE /config HTTP/1.0\" 200 491",
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "frank",
+				Date:       mustParseCommonDate("23/Mar/2019:18:44:53 +0000"),
+				Verb:       "DELETE",
+				Request:    "DELETE /config HTTP/1.0",
+				Protocol:   "HTTP/1.0",
+				Section:    "/config",
+				Path:       "/config",
+				StatusCode: 200,
+				ByteSize:   491,
+			},
+		},
+		{
+			name:   "lookup is case insensitive",
+			format: "TEST",
+			line:   "/anything",
+			want:   LogEvent{Path: "/anything"},
+		},
+		{
+			name:    "unknown format",
+			format:  "nope",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := LookupParser(tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, _ := parser.Parse(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserFunc(t *testing.T) {
+	want := errors.New("nope")
+	parser := ParserFunc(func(line string) (LogEvent, error) {
+		return LogEvent{}, want
+	})
+	if _, err := parser.Parse("x"); err != want {
+		t.Errorf("Parse() error = %v, want %v", err, want)
+	}
+}
+
+func mustParseCommonDate(value string) time.Time {
+	date, err := time.Parse(commonLogDateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
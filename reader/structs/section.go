@@ -0,0 +1,78 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// idSegmentRegex matches path segments that identify a single resource (numbers, UUIDs and long hex strings)
+var idSegmentRegex = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// SectionDepth is the number of path segments kept in a section (1 turns "/pages/create" into "/pages")
+var SectionDepth = 1
+
+// NormalizeIDs replaces segments that look like IDs with ":id" so "/users/12345" becomes "/users/:id"
+var NormalizeIDs bool
+
+// SectionRules are tried in order before SectionDepth is applied, the first rule that matches names the section
+var SectionRules []SectionRule
+
+// SectionRule rewrites the paths matching Pattern to the section Replacement (which may use $1 style references)
+type SectionRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ParseSectionRule reads a rule written as "regex=>replacement", like `^/api/v\d+/(\w+)=>/api/$1`
+func ParseSectionRule(value string) (SectionRule, error) {
+	pieces := strings.SplitN(value, "=>", 2)
+	if len(pieces) != 2 {
+		return SectionRule{}, fmt.Errorf("Bad section rule %q (expected regex=>replacement)", value)
+	}
+	pattern, err := regexp.Compile(pieces[0])
+	if err != nil {
+		return SectionRule{}, fmt.Errorf("Bad section rule %q: %v", value, err)
+	}
+	return SectionRule{Pattern: pattern, Replacement: pieces[1]}, nil
+}
+
+// apply returns the section for path and whether the rule matched it
+func (rule SectionRule) apply(path string) (string, bool) {
+	match := rule.Pattern.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", false
+	}
+	return string(rule.Pattern.ExpandString(nil, rule.Replacement, path, match)), true
+}
+
+/*
+SectionFromPath returns the section of a path. By default that is what's before the second '/' of the path,
+so "/pages/create" becomes "/pages". SectionRules, NormalizeIDs and SectionDepth change how it is worked out
+*/
+func SectionFromPath(path string) string {
+	for _, rule := range SectionRules {
+		if section, ok := rule.apply(path); ok {
+			return section
+		}
+	}
+
+	// this comes in as something like /path or /section/path so we split and try to get the pieces separately
+	pieces := strings.Split(path, "/")
+	if NormalizeIDs {
+		for i, piece := range pieces {
+			if idSegmentRegex.MatchString(piece) {
+				pieces[i] = ":id"
+			}
+		}
+	}
+
+	depth := SectionDepth
+	if depth < 1 {
+		depth = 1
+	}
+	if len(pieces) > depth+1 {
+		return "/" + strings.Join(pieces[1:depth+1], "/")
+	}
+	return strings.Join(pieces, "/")
+}
@@ -0,0 +1,46 @@
+package structs
+
+import "sort"
+
+/*
+SectionCounters keeps the running SectionDetail of every section. Counting an event is a map lookup, so the
+totals can be kept up to date as events arrive instead of being worked out again from every event
+*/
+type SectionCounters map[string]*SectionDetail
+
+// Add counts the event in the totals of its section
+func (counters SectionCounters) Add(event LogEvent) {
+	detail, ok := counters[event.Section]
+	if !ok {
+		detail = newSectionDetail(event.Section)
+		counters[event.Section] = detail
+	}
+	detail.add(event)
+}
+
+// Merge adds the totals of other to counters (other is left as it was)
+func (counters SectionCounters) Merge(other SectionCounters) {
+	for section, otherDetail := range other {
+		detail, ok := counters[section]
+		if !ok {
+			detail = newSectionDetail(section)
+			counters[section] = detail
+		}
+		detail.merge(otherDetail)
+	}
+}
+
+// Details returns the SectionDetail of every section ordered by hits (most first) and then by section
+func (counters SectionCounters) Details() []SectionDetail {
+	details := make([]SectionDetail, 0, len(counters))
+	for _, detail := range counters {
+		details = append(details, *detail)
+	}
+	sort.Slice(details, func(i, j int) bool {
+		if details[i].Hits != details[j].Hits {
+			return details[i].Hits > details[j].Hits
+		}
+		return details[i].Section < details[j].Section
+	})
+	return details
+}
@@ -0,0 +1,160 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSectionCounters(t *testing.T) {
+	events := []LogEvent{
+		{Section: "/report", StatusCode: 200, ByteSize: 100, Duration: 10 * time.Millisecond},
+		{Section: "/api", StatusCode: 500, ByteSize: 10, Error: true},
+		{Section: "/report", StatusCode: 404, ByteSize: 50, Error: true, Duration: 30 * time.Millisecond},
+		{Section: "/api", StatusCode: 200, ByteSize: 20},
+		{Section: "/users", StatusCode: 200, ByteSize: 5},
+	}
+
+	first := make(SectionCounters)
+	second := make(SectionCounters)
+	for i, event := range events {
+		// splitting the events over two counters and merging them adds up the same
+		if i%2 == 0 {
+			first.Add(event)
+		} else {
+			second.Add(event)
+		}
+	}
+	merged := make(SectionCounters)
+	merged.Merge(first)
+	merged.Merge(second)
+
+	details := merged.Details()
+	sections := make([]string, 0)
+	for _, detail := range details {
+		sections = append(sections, detail.Section)
+	}
+	// ties on hits are ordered by section
+	if want := []string{"/api", "/report", "/users"}; !reflect.DeepEqual(sections, want) {
+		t.Errorf("Details() sections = %v, want %v", sections, want)
+	}
+
+	report := details[1]
+	if report.Hits != 2 || report.Errors != 1 || report.Bytes != 150 {
+		t.Errorf("/report = %+v, want 2 hits, 1 error and 150 bytes", report)
+	}
+	if want := map[int]int{200: 1, 404: 1}; !reflect.DeepEqual(report.Statuses, want) {
+		t.Errorf("/report statuses = %v, want %v", report.Statuses, want)
+	}
+	if report.Latency == nil || report.Latency.Count() != 2 || report.Latency.Max() != 30*time.Millisecond {
+		t.Errorf("/report latency = %+v, want 2 durations up to 30ms", report.Latency)
+	}
+	if details[0].Latency != nil {
+		t.Errorf("/api latency = %+v, want none", details[0].Latency)
+	}
+
+	// merging leaves the counters merged in alone
+	if got := first["/report"].Hits; got != 2 {
+		t.Errorf("first /report hits = %v after merging, want 2", got)
+	}
+}
+
+func TestEventStoreSections(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	store := NewEventStore(10)
+	trailing := make([]LogEvent, 0)
+	for seconds := 20; seconds >= 0; seconds-- {
+		event := LogEvent{Date: now.Add(-time.Duration(seconds) * time.Second), Section: fmt.Sprintf("/%d", seconds%2), StatusCode: 200}
+		store.Add(event)
+		if seconds <= 10 {
+			trailing = append(trailing, event)
+		}
+	}
+
+	sections := store.Sections(10)
+	if got := sections["/0"].Hits; got != 6 {
+		t.Errorf("/0 hits = %v, want 6", got)
+	}
+	if got := sections["/1"].Hits; got != 5 {
+		t.Errorf("/1 hits = %v, want 5", got)
+	}
+	if !reflect.DeepEqual(store.Sections(10).Details(), GroupBySection(trailing)) {
+		t.Error("Sections() does not add up to GroupBySection() of the same events")
+	}
+}
+
+// benchmarkEvents makes count events spread over 50 sections and a few status codes
+func benchmarkEvents(count int) []LogEvent {
+	events := make([]LogEvent, count)
+	statuses := []int{200, 200, 200, 304, 404, 500}
+	for index := range events {
+		status := statuses[index%len(statuses)]
+		events[index] = LogEvent{
+			Section:    fmt.Sprintf("/section%d", index%50),
+			StatusCode: status,
+			Error:      status >= 400,
+			ByteSize:   index % 2048,
+			Duration:   time.Duration(index%1000) * time.Millisecond,
+		}
+	}
+	return events
+}
+
+// benchmarkSizes are the numbers of events each benchmark runs over, ns/op divided by the count is the cost per event
+var benchmarkSizes = []struct {
+	name  string
+	count int
+}{
+	{"10k", 10000},
+	{"100k", 100000},
+	{"1M", 1000000},
+}
+
+func BenchmarkGroupBySection(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		events := benchmarkEvents(size.count)
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				GroupBySection(events)
+			}
+		})
+	}
+}
+
+func BenchmarkEventStoreSections(b *testing.B) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	for _, size := range benchmarkSizes {
+		// the events are spread over the 10 seconds the store holds
+		events := benchmarkEvents(size.count)
+		for index := range events {
+			events[index].Date = now.Add(-time.Duration(index%10) * time.Second)
+		}
+
+		b.Run(size.name+"/add", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				store := NewEventStore(10)
+				for _, event := range events {
+					store.Add(event)
+				}
+			}
+		})
+
+		store := NewEventStore(10)
+		for _, event := range events {
+			store.Add(event)
+		}
+		b.Run(size.name+"/sections", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				store.Sections(10).Details()
+			}
+		})
+	}
+}
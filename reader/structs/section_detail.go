@@ -1,55 +1,54 @@
 package structs
 
-import (
-	"sort"
-)
-
-// SectionDetail represents all the events for a particular path
+// SectionDetail represents the totals of all the events for a particular section
 type SectionDetail struct {
 	Section string
 	Hits    int
 	Errors  int
-	Events  []LogEvent
-}
-
-// SectionDetailBy is the type of a "less" function that defines the ordering of its arguments.
-type SectionDetailBy func(p1, p2 *SectionDetail) bool
-
-// Sort is a method on the function type, By, that sorts the argument slice according to the function.
-func (by SectionDetailBy) Sort(details []SectionDetail) {
-	es := &sectionDetailSorter{
-		details: details,
-		by:      by, // The Sort method's receiver is the function (closure) that defines the sort order.
-	}
-	sort.Sort(es)
-}
-
-type sectionDetailSorter struct {
-	details []SectionDetail
-	by      func(p1, p2 *SectionDetail) bool // Closure used in the Less method.
+	Bytes   int
+	// Statuses counts the hits of every status code
+	Statuses map[int]int
+	// Latency holds the response times of the section, it is nil when the log has none
+	Latency *LatencySketch
+	// Visitors estimates the unique hosts and users of the section
+	Visitors Visitors
 }
 
-// Len is part of sort.Interface.
-func (s *sectionDetailSorter) Len() int {
-	return len(s.details)
+// newSectionDetail returns an empty SectionDetail for section
+func newSectionDetail(section string) *SectionDetail {
+	return &SectionDetail{Section: section, Statuses: make(map[int]int)}
 }
 
-// Swap is part of sort.Interface.
-func (s *sectionDetailSorter) Swap(i, j int) {
-	s.details[i], s.details[j] = s.details[j], s.details[i]
-}
-
-// Less is part of sort.Interface. It is implemented by calling the "by" closure in the sorter.
-func (s *sectionDetailSorter) Less(i, j int) bool {
-	return s.by(&s.details[i], &s.details[j])
+// add counts the event in the totals
+func (detail *SectionDetail) add(event LogEvent) {
+	detail.Hits++
+	if event.Error {
+		detail.Errors++
+	}
+	detail.Bytes += event.ByteSize
+	detail.Statuses[event.StatusCode]++
+	detail.Visitors.Add(event)
+	if event.Duration > 0 {
+		if detail.Latency == nil {
+			detail.Latency = NewLatencySketch()
+		}
+		detail.Latency.Add(event.Duration)
+	}
 }
 
-// SortSectionDetailsByHitsDesc returns the log events sorted by section
-func SortSectionDetailsByHitsDesc(details []SectionDetail) []SectionDetail {
-	hits := func(p1, p2 *SectionDetail) bool {
-		return p1.Hits > p2.Hits
+// merge adds the totals of other to detail
+func (detail *SectionDetail) merge(other *SectionDetail) {
+	detail.Hits += other.Hits
+	detail.Errors += other.Errors
+	detail.Bytes += other.Bytes
+	for status, hits := range other.Statuses {
+		detail.Statuses[status] += hits
+	}
+	detail.Visitors.Merge(other.Visitors)
+	if other.Latency != nil {
+		if detail.Latency == nil {
+			detail.Latency = NewLatencySketch()
+		}
+		detail.Latency.Merge(other.Latency)
 	}
-
-	SectionDetailBy(hits).Sort(details)
-	return details
 }
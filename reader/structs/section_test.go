@@ -0,0 +1,54 @@
+package structs
+
+import (
+	"testing"
+)
+
+func TestSectionFromPath(t *testing.T) {
+	versioned, err := ParseSectionRule(`^/api/v\d+/(\w+)=>/api/$1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		SectionDepth = 1
+		NormalizeIDs = false
+		SectionRules = nil
+	}()
+
+	tests := []struct {
+		name         string
+		depth        int
+		normalizeIDs bool
+		rules        []SectionRule
+		path         string
+		want         string
+	}{
+		{name: "default", depth: 1, path: "/pages/create", want: "/pages"},
+		{name: "default single segment", depth: 1, path: "/config", want: "/config"},
+		{name: "default root", depth: 1, path: "/", want: "/"},
+		{name: "depth 2", depth: 2, path: "/api/v1/users/12345", want: "/api/v1"},
+		{name: "depth 2 short path", depth: 2, path: "/api/v1", want: "/api/v1"},
+		{name: "ids", depth: 2, normalizeIDs: true, path: "/users/12345/orders", want: "/users/:id"},
+		{name: "uuid", depth: 3, normalizeIDs: true, path: "/users/123e4567-e89b-12d3-a456-426614174000", want: "/users/:id"},
+		{name: "rule", depth: 1, rules: []SectionRule{versioned}, path: "/api/v2/users/12345", want: "/api/users"},
+		{name: "rule does not match", depth: 1, rules: []SectionRule{versioned}, path: "/api/users", want: "/api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SectionDepth = tt.depth
+			NormalizeIDs = tt.normalizeIDs
+			SectionRules = tt.rules
+			if got := SectionFromPath(tt.path); got != tt.want {
+				t.Errorf("SectionFromPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSectionRule(t *testing.T) {
+	for _, value := range []string{"no arrow", "(=>/broken"} {
+		if _, err := ParseSectionRule(value); err == nil {
+			t.Errorf("ParseSectionRule(%q) expected an error", value)
+		}
+	}
+}
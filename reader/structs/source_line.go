@@ -0,0 +1,15 @@
+package structs
+
+// SourceLine is a raw line along with the log source it was read from
+type SourceLine struct {
+	Source string
+	Text   string
+	// EOF is set (with an empty Text) when a source that cannot be followed any further, like stdin, has ended
+	EOF bool
+	// Rotated is set when a file was rotated and is read again from its start, Text then says how it was rotated
+	Rotated bool
+	// Inode and Offset are, for the lines of a followed file, the file the line was read from and where the line
+	// after it starts there, so the line is only checkpointed once it has been ingested
+	Inode  uint64
+	Offset int64
+}
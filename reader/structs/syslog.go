@@ -0,0 +1,75 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rfc5424 matches <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+var rfc5424 = regexp.MustCompile(`^<(\d{1,3})>\d{1,2} (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (.*))?$`)
+
+// rfc3164 matches <PRI>Mmm dd hh:mm:ss [HOSTNAME] TAG[PID]: MSG
+var rfc3164 = regexp.MustCompile(`^<(\d{1,3})>([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (?:(\S+) )?([^\s:\[]+)(?:\[\d+\])?: ?(.*)$`)
+
+// rfc3164Priority matches a bare <PRI> for senders that do not follow RFC 3164 any further
+var rfc3164Priority = regexp.MustCompile(`^<(\d{1,3})>(.*)$`)
+
+// SyslogMessage is a syslog message (RFC 3164 or RFC 5424) with its envelope taken apart
+type SyslogMessage struct {
+	Priority int
+	Hostname string
+	AppName  string
+	// Message is the payload, the access log line when nginx logs with access_log syslog:server=...
+	Message string
+}
+
+/*
+ParseSyslogMessage strips the syslog envelope off a message. RFC 5424 is tried first, then RFC 3164 (the
+BSD format nginx sends), where the hostname is optional, and finally a bare <PRI> prefix
+
+	<190>Mar 23 18:44:53 web-1 nginx: 127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /report HTTP/1.0" 200 123
+	<190>1 2019-03-23T18:44:53Z web-1 nginx - - - 127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /report HTTP/1.0" 200 123
+*/
+func ParseSyslogMessage(line string) (SyslogMessage, error) {
+	line = strings.TrimRight(line, "\r\n\x00")
+
+	if match := rfc5424.FindStringSubmatch(line); match != nil {
+		return SyslogMessage{
+			Priority: syslogPriority(match[1]),
+			Hostname: syslogNil(match[3]),
+			AppName:  syslogNil(match[4]),
+			// the message may start with a byte order mark to say it is UTF-8
+			Message: strings.TrimPrefix(match[8], "\ufeff"),
+		}, nil
+	}
+
+	if match := rfc3164.FindStringSubmatch(line); match != nil {
+		return SyslogMessage{
+			Priority: syslogPriority(match[1]),
+			Hostname: match[3],
+			AppName:  match[4],
+			Message:  match[5],
+		}, nil
+	}
+
+	if match := rfc3164Priority.FindStringSubmatch(line); match != nil {
+		return SyslogMessage{Priority: syslogPriority(match[1]), Message: match[2]}, nil
+	}
+	return SyslogMessage{}, fmt.Errorf("Not a syslog message: %q", line)
+}
+
+// syslogPriority reads the <PRI> (facility * 8 + severity), it is at most 3 digits so it always converts
+func syslogPriority(value string) int {
+	priority, _ := strconv.Atoi(value)
+	return priority
+}
+
+// syslogNil turns the "-" RFC 5424 uses for a missing value into an empty string
+func syslogNil(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSyslogMessage(t *testing.T) {
+	access := `127.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /report HTTP/1.0" 200 123`
+	tests := []struct {
+		name    string
+		line    string
+		want    SyslogMessage
+		wantErr bool
+	}{
+		{
+			name: "RFC 3164 from nginx",
+			line: "<190>Mar 23 18:44:53 web-1 nginx: " + access,
+			want: SyslogMessage{Priority: 190, Hostname: "web-1", AppName: "nginx", Message: access},
+		},
+		{
+			name: "RFC 3164 with a pid and a single digit day",
+			line: "<190>Mar  3 08:04:05 web-1 nginx[1234]: " + access + "\n",
+			want: SyslogMessage{Priority: 190, Hostname: "web-1", AppName: "nginx", Message: access},
+		},
+		{
+			name: "RFC 3164 without a hostname",
+			line: "<190>Mar 23 18:44:53 nginx: " + access,
+			want: SyslogMessage{Priority: 190, AppName: "nginx", Message: access},
+		},
+		{
+			name: "RFC 5424",
+			line: "<190>1 2019-03-23T18:44:53.003Z web-1 nginx 1234 access - " + access,
+			want: SyslogMessage{Priority: 190, Hostname: "web-1", AppName: "nginx", Message: access},
+		},
+		{
+			name: "RFC 5424 with structured data and a BOM",
+			line: `<165>1 2019-03-23T18:44:53Z - nginx - - [exampleSDID@32473 iut="3" eventSource="App\]"][meta x="1"] ` + "\ufeff" + access,
+			want: SyslogMessage{Priority: 165, AppName: "nginx", Message: access},
+		},
+		{
+			name: "bare priority",
+			line: "<13>" + access,
+			want: SyslogMessage{Priority: 13, Message: access},
+		},
+		{
+			name:    "no envelope",
+			line:    access,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSyslogMessage(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSyslogMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSyslogMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateVariableRegex finds nginx ($name or ${name}) and Apache (%h, %>s, %{Referer}i...) variables in a log format
+var templateVariableRegex = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)|%(\{[^}]*\})?[<>]?([a-zA-Z%])`)
+
+// the names used internally for the variables that fill LogEvent fields
+const (
+	templateHost          = "host"
+	templateUser          = "user"
+	templateDate          = "date"
+	templateRequest       = "request"
+	templateVerb          = "verb"
+	templatePath          = "path"
+	templateTarget        = "target"
+	templateQuery         = "query"
+	templateProtocol      = "protocol"
+	templateStatus        = "status"
+	templateBytes         = "bytes"
+	templateReferer       = "referer"
+	templateUserAgent     = "user_agent"
+	templateSeconds       = "seconds"
+	templateMilliseconds  = "milliseconds"
+	templateMicroseconds  = "microseconds"
+	templateBracketedDate = "bracketed_date"
+	templateIgnored       = "ignored"
+	templateExtra         = "extra"
+)
+
+// nginxVariables maps nginx log_format variables onto LogEvent fields
+var nginxVariables = map[string]string{
+	"remote_addr":     templateHost,
+	"remote_user":     templateUser,
+	"time_local":      templateDate,
+	"time_iso8601":    templateDate,
+	"request":         templateRequest,
+	"request_method":  templateVerb,
+	"request_uri":     templateTarget,
+	"uri":             templatePath,
+	"args":            templateQuery,
+	"query_string":    templateQuery,
+	"server_protocol": templateProtocol,
+	"status":          templateStatus,
+	"body_bytes_sent": templateBytes,
+	"bytes_sent":      templateBytes,
+	"http_referer":    templateReferer,
+	"http_user_agent": templateUserAgent,
+	"request_time":    templateSeconds,
+}
+
+// apacheDirectives maps Apache LogFormat directives onto LogEvent fields
+var apacheDirectives = map[string]string{
+	"h":             templateHost,
+	"a":             templateHost,
+	"l":             templateIgnored,
+	"u":             templateUser,
+	"t":             templateBracketedDate,
+	"r":             templateRequest,
+	"m":             templateVerb,
+	"U":             templatePath,
+	"q":             templateQuery,
+	"H":             templateProtocol,
+	"s":             templateStatus,
+	"b":             templateBytes,
+	"B":             templateBytes,
+	"D":             templateMicroseconds,
+	"T":             templateSeconds,
+	"{s}T":          templateSeconds,
+	"{ms}T":         templateMilliseconds,
+	"{us}T":         templateMicroseconds,
+	"{Referer}i":    templateReferer,
+	"{User-Agent}i": templateUserAgent,
+}
+
+// templateVariable is a single capture group of a compiled TemplateParser
+type templateVariable struct {
+	// field is one of the template constants above
+	field string
+	// name is the variable as written in the format ("upstream_addr", "{X-Forwarded-For}i"...) used as the Extra key
+	name string
+}
+
+/*
+TemplateParser parses lines written with a custom nginx log_format or Apache LogFormat.
+Variables logtop does not know about end up in LogEvent.Extra under their name
+*/
+type TemplateParser struct {
+	regex     *regexp.Regexp
+	variables []templateVariable
+}
+
+/*
+NewTemplateParser compiles a log format like
+
+	$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent $request_time
+
+or
+
+	%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i" %D
+
+into a TemplateParser. The format has to contain a date ($time_local, $time_iso8601 or %t), without one the events
+could not be placed in the statistics window
+*/
+func NewTemplateParser(format string) (*TemplateParser, error) {
+	matches := templateVariableRegex.FindAllStringSubmatchIndex(format, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("Log format %q does not contain any variables", format)
+	}
+
+	parser := &TemplateParser{}
+	hasDate := false
+	pattern := "^"
+	last := 0
+	for i, match := range matches {
+		pattern += regexp.QuoteMeta(format[last:match[0]])
+		last = match[1]
+
+		// work out what comes after this variable so the capture can stop there
+		next := ""
+		if i+1 < len(matches) {
+			next = format[last:matches[i+1][0]]
+		} else {
+			next = format[last:]
+		}
+
+		var variable templateVariable
+		switch {
+		case match[2] >= 0:
+			variable = nginxVariable(format[match[2]:match[3]])
+		case match[4] >= 0:
+			variable = nginxVariable(format[match[4]:match[5]])
+		default:
+			directive := format[match[8]:match[9]]
+			if directive == "%" {
+				pattern += "%"
+				continue
+			}
+			if match[6] >= 0 {
+				directive = format[match[6]:match[7]] + directive
+			}
+			variable = apacheVariable(directive)
+		}
+
+		switch variable.field {
+		case templateBracketedDate:
+			pattern += `\[([^\]]*)\]`
+			hasDate = true
+		case templateDate:
+			pattern += "(" + templateDateCapture(next) + ")"
+			hasDate = true
+		default:
+			pattern += "(" + templateCapture(next) + ")"
+		}
+		parser.variables = append(parser.variables, variable)
+	}
+	pattern += regexp.QuoteMeta(format[last:]) + "$"
+	if !hasDate {
+		return nil, fmt.Errorf("Log format %q does not contain a date ($time_local, $time_iso8601 or %%t)", format)
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	parser.regex = regex
+	return parser, nil
+}
+
+// ExtraFields returns the names of the variables that end up in LogEvent.Extra, in the order of the format
+func (parser *TemplateParser) ExtraFields() []string {
+	fields := make([]string, 0)
+	for _, variable := range parser.variables {
+		if variable.field == templateExtra {
+			fields = append(fields, variable.name)
+		}
+	}
+	return fields
+}
+
+// nginxVariable looks up an nginx variable
+func nginxVariable(name string) templateVariable {
+	field, ok := nginxVariables[name]
+	if !ok {
+		return templateVariable{field: templateExtra, name: name}
+	}
+	return templateVariable{field: field, name: name}
+}
+
+// apacheVariable looks up an Apache directive, dropping the braces from the name of unknown headers
+func apacheVariable(directive string) templateVariable {
+	field, ok := apacheDirectives[directive]
+	if !ok {
+		name := directive
+		if strings.HasPrefix(directive, "{") {
+			name = directive[1:strings.Index(directive, "}")]
+		}
+		return templateVariable{field: templateExtra, name: name}
+	}
+	return templateVariable{field: field, name: directive}
+}
+
+// templateCapture returns the regex for a value followed by the literal text next
+func templateCapture(next string) string {
+	switch {
+	case next == "":
+		return ".*"
+	case next[0] == ' ' || next[0] == '\t':
+		return `\S*`
+	default:
+		return "[^" + regexp.QuoteMeta(next[:1]) + "]*"
+	}
+}
+
+/*
+templateDateCapture returns the regex for a date followed by the literal text next. $time_local has a space before
+its zone ("23/Mar/2019:18:44:53 +0000"), which is taken in when the date is not in brackets
+*/
+func templateDateCapture(next string) string {
+	if next != "" && (next[0] == ' ' || next[0] == '\t') {
+		return `\S*(?: [+-]\d{4})?`
+	}
+	return templateCapture(next)
+}
+
+// Parse takes the log string and returns a LogEvent filled from the variables of the format
+func (parser *TemplateParser) Parse(line string) (LogEvent, error) {
+	if line == "" {
+		return LogEvent{}, newParseError(RejectEmpty, "Empty String")
+	}
+
+	line = strings.ReplaceAll(line, "\n", "")
+
+	result := parser.regex.FindStringSubmatch(line)
+	if result == nil {
+		return LogEvent{}, newParseError(RejectMismatch, "Bad regex")
+	}
+
+	event := LogEvent{}
+	for i, variable := range parser.variables {
+		if err := variable.apply(&event, result[i+1]); err != nil {
+			return LogEvent{}, err
+		}
+	}
+
+	event.Section = SectionFromPath(event.Path)
+	event.Error = event.StatusCode >= 400
+	return event, nil
+}
+
+// apply sets the field of event this variable maps to
+func (variable templateVariable) apply(event *LogEvent, value string) error {
+	switch variable.field {
+	case templateExtra:
+		if event.Extra == nil {
+			event.Extra = make(map[string]string)
+		}
+		event.Extra[variable.name] = value
+	case templateHost:
+		event.Host = value
+		event.Address = ParseAddress(value)
+	case templateUser:
+		event.User = value
+	case templateDate, templateBracketedDate:
+		date, err := ParseTimestamp(value)
+		if err != nil {
+			return err
+		}
+		event.Date = date
+	case templateRequest:
+		verb, target, protocol, ok := ParseRequestLine(value)
+		if !ok {
+			return newParseError(RejectMismatch, "Bad request %q", value)
+		}
+		event.Request = value
+		event.Verb = verb
+		event.Protocol = protocol
+		event.setTarget(target)
+	case templateVerb:
+		event.Verb = value
+	case templatePath:
+		event.Path = value
+	case templateTarget:
+		event.setTarget(value)
+	case templateQuery:
+		event.setRawQuery(value)
+	case templateProtocol:
+		event.Protocol = value
+	case templateStatus:
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			return newParseError(RejectStatus, "Bad status %q", value)
+		}
+		event.StatusCode = status
+	case templateBytes:
+		// "-" (no bytes sent) is left as 0
+		event.ByteSize, _ = strconv.Atoi(value)
+	case templateReferer:
+		event.Referer = unquoteLogValue(value)
+	case templateUserAgent:
+		event.UserAgent = unquoteLogValue(value)
+	case templateSeconds:
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			event.Duration = time.Duration(seconds * float64(time.Second))
+		}
+	case templateMilliseconds:
+		milliseconds, err := strconv.Atoi(value)
+		if err == nil {
+			event.Duration = time.Duration(milliseconds) * time.Millisecond
+		}
+	case templateMicroseconds:
+		microseconds, err := strconv.Atoi(value)
+		if err == nil {
+			event.Duration = time.Duration(microseconds) * time.Microsecond
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package structs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTemplateParserParse(t *testing.T) {
+	date := mustParseCommonDate("23/Mar/2019:18:44:53 +0000")
+
+	tests := []struct {
+		name    string
+		format  string
+		line    string
+		want    LogEvent
+		wantErr bool
+	}{
+		{
+			name:   "nginx with request time",
+			format: `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent $request_time`,
+			line:   `10.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /api/user?id=1 HTTP/1.1" 503 491 0.250`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /api/user?id=1 HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/api",
+				Path:       "/api/user",
+				RawQuery:   "id=1",
+				Query:      url.Values{"id": []string{"1"}},
+				StatusCode: 503,
+				ByteSize:   491,
+				Error:      true,
+				Duration:   250 * time.Millisecond,
+			},
+		},
+		{
+			name:   "nginx with unknown variables",
+			format: `$remote_addr [$time_iso8601] "$request_method ${uri}" $status $upstream_addr "$http_user_agent"`,
+			line:   `10.0.0.1 [2019-03-23T18:44:53Z] "POST /report" 200 10.0.0.2:8080 "curl/7.64"`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				Date:       time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC),
+				Verb:       "POST",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				UserAgent:  "curl/7.64",
+				Extra:      map[string]string{"upstream_addr": "10.0.0.2:8080"},
+			},
+		},
+		{
+			name:   "Apache combined with %D",
+			format: `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i" %{X-Request-Id}i %D`,
+			line:   `10.0.0.1 - frank [23/Mar/2019:18:44:53 +0000] "GET /config/update HTTP/1.0" 200 - "-" "Mozilla/5.0 (X11)" abc123 1500`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /config/update HTTP/1.0",
+				Protocol:   "HTTP/1.0",
+				Section:    "/config",
+				Path:       "/config/update",
+				StatusCode: 200,
+				UserAgent:  "Mozilla/5.0 (X11)",
+				Duration:   1500 * time.Microsecond,
+				Extra:      map[string]string{"X-Request-Id": "abc123"},
+			},
+		},
+		{
+			name:   "nginx with an unbracketed date",
+			format: `$remote_addr $time_local $status $request_time`,
+			line:   `10.0.0.1 23/Mar/2019:18:44:53 +0000 200 0.250`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				Date:       date,
+				StatusCode: 200,
+				Duration:   250 * time.Millisecond,
+			},
+		},
+		{
+			name:   "Apache with %{ms}T",
+			format: `%h %t "%r" %>s %{ms}T`,
+			line:   `10.0.0.1 [23/Mar/2019:18:44:53 +0000] "GET /report HTTP/1.1" 200 1500`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				Date:       date,
+				Verb:       "GET",
+				Request:    "GET /report HTTP/1.1",
+				Protocol:   "HTTP/1.1",
+				Section:    "/report",
+				Path:       "/report",
+				StatusCode: 200,
+				Duration:   1500 * time.Millisecond,
+			},
+		},
+		{
+			name:   "Apache with %{us}T",
+			format: `%h %t %>s %{us}T`,
+			line:   `10.0.0.1 [23/Mar/2019:18:44:53 +0000] 200 1500`,
+			want: LogEvent{
+				Host:       "10.0.0.1",
+				Address:    ParseAddress("10.0.0.1"),
+				Date:       date,
+				StatusCode: 200,
+				Duration:   1500 * time.Microsecond,
+			},
+		},
+		{
+			name:    "Line does not match",
+			format:  `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`,
+			line:    `10.0.0.1 frank GET /`,
+			wantErr: true,
+		},
+		{
+			name:    "Bad date",
+			format:  `%h %t "%r" %s`,
+			line:    `10.0.0.1 [yesterday] "GET / HTTP/1.0" 200`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewTemplateParser(tt.format)
+			if err != nil {
+				t.Fatalf("NewTemplateParser() error = %v", err)
+			}
+			got, err := parser.Parse(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTemplateParserWithoutVariables(t *testing.T) {
+	if _, err := NewTemplateParser("just text"); err == nil {
+		t.Error("NewTemplateParser() expected an error")
+	}
+}
+
+func TestNewTemplateParserWithoutDate(t *testing.T) {
+	if _, err := NewTemplateParser(`$remote_addr "$request" $status`); err == nil {
+		t.Error("NewTemplateParser() expected an error for a format without a date")
+	}
+}
+
+func TestTemplateParserExtraFields(t *testing.T) {
+	parser, err := NewTemplateParser(`$remote_addr [$time_local] "$request" $status $upstream_addr "$http_x_forwarded_for"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parser.ExtraFields(), []string{"upstream_addr", "http_x_forwarded_for"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtraFields() = %v, want %v", got, want)
+	}
+}
+
+func TestLogEventField(t *testing.T) {
+	event := LogEvent{Host: "10.0.0.1", StatusCode: 404, Extra: map[string]string{"upstream_addr": "10.0.0.2"}}
+	tests := []struct {
+		name  string
+		want  string
+		known bool
+	}{
+		{name: "host", want: "10.0.0.1", known: true},
+		{name: "Status", want: "404", known: true},
+		{name: "upstream_addr", want: "10.0.0.2"},
+		{name: "missing", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := event.Field(tt.name); got != tt.want {
+				t.Errorf("Field() = %v, want %v", got, tt.want)
+			}
+			if got := IsEventField(tt.name); got != tt.known {
+				t.Errorf("IsEventField() = %v, want %v", got, tt.known)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package structs
+
+import (
+	"time"
+)
+
+// zonedLayouts are tried in order by ParseTimestamp, fractional seconds are accepted by each of them
+var zonedLayouts = []string{
+	commonLogDateLayout,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// zonelessLayouts are tried after zonedLayouts and interpreted in TimestampLocation
+var zonelessLayouts = []string{
+	"02/Jan/2006:15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// TimestampLocation is the time zone of timestamps that do not include one
+var TimestampLocation = time.Local
+
+/*
+ParseTimestamp parses the Common Log Format date (23/Mar/2019:18:44:53 +0000) or ISO8601, with or without
+milliseconds and with or without a zone (see TimestampLocation). A date that matches none of them is a ParseError
+*/
+func ParseTimestamp(value string) (time.Time, error) {
+	for _, layout := range zonedLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	for _, layout := range zonelessLayouts {
+		if date, err := time.ParseInLocation(layout, value, TimestampLocation); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, newParseError(RejectDate, "Bad date %q", value)
+}
@@ -0,0 +1,52 @@
+package structs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+	TimestampLocation = newYork
+	defer func() { TimestampLocation = time.Local }()
+
+	want := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	withMillis := want.Add(250 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "Common Log Format", value: "23/Mar/2019:18:44:53 +0000", want: want},
+		{name: "Common Log Format with offset", value: "23/Mar/2019:14:44:53 -0400", want: want},
+		{name: "Common Log Format with milliseconds", value: "23/Mar/2019:18:44:53.250 +0000", want: withMillis},
+		{name: "RFC3339", value: "2019-03-23T18:44:53Z", want: want},
+		{name: "ISO8601 without colon", value: "2019-03-23T18:44:53.250+0000", want: withMillis},
+		{name: "Common Log Format without zone", value: "23/Mar/2019:14:44:53", want: want},
+		{name: "ISO8601 without zone", value: "2019-03-23T14:44:53.250", want: withMillis},
+		{name: "Garbage", value: "yesterday", wantErr: true},
+		{name: "Impossible date", value: "31/Feb/2019:18:44:53 +0000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if RejectReasonOf(err) != RejectDate {
+					t.Errorf("ParseTimestamp() error = %v, want a RejectDate ParseError", err)
+				}
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package structs
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// TopKEntry is a value counted by a TopK, its true number of hits is between Hits - Error and Hits
+type TopKEntry struct {
+	Value string
+	Hits  int
+	Error int
+}
+
+// topKCounter is a TopKEntry along with where it sits in the heap
+type topKCounter struct {
+	TopKEntry
+	index int
+}
+
+// topKHeap is a min-heap of the counters by hits, so the least counted value is the one replaced
+type topKHeap []*topKCounter
+
+// Len is part of sort.Interface.
+func (entries topKHeap) Len() int {
+	return len(entries)
+}
+
+// Less is part of sort.Interface.
+func (entries topKHeap) Less(i, j int) bool {
+	return entries[i].Hits < entries[j].Hits
+}
+
+// Swap is part of sort.Interface.
+func (entries topKHeap) Swap(i, j int) {
+	entries[i], entries[j] = entries[j], entries[i]
+	entries[i].index = i
+	entries[j].index = j
+}
+
+// Push is part of heap.Interface.
+func (entries *topKHeap) Push(entry interface{}) {
+	typed := entry.(*topKCounter)
+	typed.index = len(*entries)
+	*entries = append(*entries, typed)
+}
+
+// Pop is part of heap.Interface.
+func (entries *topKHeap) Pop() interface{} {
+	old := *entries
+	entry := old[len(old)-1]
+	*entries = old[:len(old)-1]
+	return entry
+}
+
+/*
+TopK finds the most frequent values of a stream with the Space-Saving algorithm. It keeps at most capacity
+counters however many distinct values there are: once they are all in use, a new value takes over the counter of
+the least counted one, inheriting its hits as its Error. Counts are exact until then, and afterwards every value
+seen more than Total() / capacity times is guaranteed to be kept
+*/
+type TopK struct {
+	capacity int
+	entries  map[string]*topKCounter
+	heap     topKHeap
+	total    int
+	// floor is how many hits a value without a counter may have had in the sketches merged into this one
+	floor int
+}
+
+// NewTopK returns an empty TopK keeping at most capacity counters (its memory budget)
+func NewTopK(capacity int) *TopK {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &TopK{
+		capacity: capacity,
+		entries:  make(map[string]*topKCounter, capacity),
+		heap:     make(topKHeap, 0, capacity),
+	}
+}
+
+// Add counts one hit of value
+func (sketch *TopK) Add(value string) {
+	sketch.total++
+	if entry, ok := sketch.entries[value]; ok {
+		entry.Hits++
+		heap.Fix(&sketch.heap, entry.index)
+		return
+	}
+
+	if len(sketch.heap) < sketch.capacity {
+		entry := &topKCounter{TopKEntry: TopKEntry{Value: value, Hits: 1}}
+		sketch.entries[value] = entry
+		heap.Push(&sketch.heap, entry)
+		return
+	}
+
+	// replace the least counted value, which may have been seen up to its hits times
+	entry := sketch.heap[0]
+	delete(sketch.entries, entry.Value)
+	entry.Value = value
+	entry.Error = entry.Hits
+	entry.Hits++
+	sketch.entries[value] = entry
+	heap.Fix(&sketch.heap, 0)
+}
+
+// Top returns at most limit entries, most hits first (ties are broken alphabetically so the UI does not jump around)
+func (sketch *TopK) Top(limit int) []TopKEntry {
+	entries := make([]TopKEntry, 0, len(sketch.heap))
+	for _, counter := range sketch.heap {
+		entries = append(entries, counter.TopKEntry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Hits == entries[j].Hits {
+			return entries[i].Value < entries[j].Value
+		}
+		return entries[i].Hits > entries[j].Hits
+	})
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+/*
+Merge adds the counts of other to sketch, so the sketches of every second of a window add up to the sketch of the
+window. A value that only one of them has a counter for may have been seen up to the ErrorBound() of the other,
+which is added to its hits and its error. The capacity most counted values are kept and the bound of the ones let go
+carries on in the ErrorBound() of sketch
+*/
+func (sketch *TopK) Merge(other *TopK) {
+	mine, theirs := sketch.ErrorBound(), other.ErrorBound()
+	merged := make(map[string]TopKEntry, len(sketch.heap)+len(other.heap))
+	for _, counter := range sketch.heap {
+		entry := counter.TopKEntry
+		if _, ok := other.entries[entry.Value]; !ok {
+			entry.Hits += theirs
+			entry.Error += theirs
+		}
+		merged[entry.Value] = entry
+	}
+	for _, counter := range other.heap {
+		entry, ok := merged[counter.Value]
+		if !ok {
+			entry = TopKEntry{Value: counter.Value, Hits: mine, Error: mine}
+		}
+		entry.Hits += counter.Hits
+		entry.Error += counter.Error
+		merged[counter.Value] = entry
+	}
+
+	entries := make([]TopKEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Hits > entries[j].Hits
+	})
+
+	sketch.floor = mine + theirs
+	if len(entries) > sketch.capacity {
+		if dropped := entries[sketch.capacity].Hits; dropped > sketch.floor {
+			sketch.floor = dropped
+		}
+		entries = entries[:sketch.capacity]
+	}
+	sketch.entries = make(map[string]*topKCounter, sketch.capacity)
+	sketch.heap = make(topKHeap, 0, sketch.capacity)
+	for _, entry := range entries {
+		counter := &topKCounter{TopKEntry: entry, index: len(sketch.heap)}
+		sketch.entries[entry.Value] = counter
+		sketch.heap = append(sketch.heap, counter)
+	}
+	heap.Init(&sketch.heap)
+	sketch.total += other.total
+}
+
+/*
+ErrorBound returns the most any count can be off by: 0 while every value has its own counter, afterwards the
+hits of the least counted entry (which is at most Total() / capacity). A merged sketch can also be off by what the
+sketches merged into it could have been
+*/
+func (sketch *TopK) ErrorBound() int {
+	if len(sketch.heap) < sketch.capacity || sketch.heap[0].Hits < sketch.floor {
+		return sketch.floor
+	}
+	return sketch.heap[0].Hits
+}
+
+// Total returns the number of hits added
+func (sketch *TopK) Total() int {
+	return sketch.total
+}
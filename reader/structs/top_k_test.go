@@ -0,0 +1,146 @@
+package structs
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTopKExactWithinCapacity(t *testing.T) {
+	sketch := NewTopK(10)
+	for _, value := range []string{"/a", "/b", "/a", "/c", "/b", "/a"} {
+		sketch.Add(value)
+	}
+
+	want := []TopKEntry{{Value: "/a", Hits: 3}, {Value: "/b", Hits: 2}}
+	if got := sketch.Top(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top() = %+v, want %+v", got, want)
+	}
+	if got := sketch.ErrorBound(); got != 0 {
+		t.Errorf("ErrorBound() = %v, want 0", got)
+	}
+	if got := sketch.Total(); got != 6 {
+		t.Errorf("Total() = %v, want 6", got)
+	}
+}
+
+func TestTopKHeavyHitters(t *testing.T) {
+	// a few heavy paths drowned in thousands of paths seen once
+	heavy := map[string]int{"/report": 500, "/api": 250, "/login": 200}
+	exact := make(map[string]int)
+	sketch := NewTopK(50)
+	for index := 0; index < 5000; index++ {
+		value := fmt.Sprintf("/users/%d", index)
+		switch {
+		case index%10 == 0:
+			value = "/report"
+		case index%20 == 1:
+			value = "/api"
+		case index%25 == 2:
+			value = "/login"
+		}
+		exact[value]++
+		sketch.Add(value)
+	}
+	for value, hits := range heavy {
+		if exact[value] != hits {
+			t.Fatalf("the stream has %v hits of %v, want %v", exact[value], value, hits)
+		}
+	}
+
+	bound := sketch.ErrorBound()
+	if bound == 0 || bound > sketch.Total()/50 {
+		t.Errorf("ErrorBound() = %v, want between 1 and %v", bound, sketch.Total()/50)
+	}
+
+	top := sketch.Top(3)
+	wantValues := []string{"/report", "/api", "/login"}
+	for i, entry := range top {
+		if entry.Value != wantValues[i] {
+			t.Errorf("Top()[%v] = %v, want %v", i, entry.Value, wantValues[i])
+		}
+		// the true count is within the entry's error, which is within the bound
+		if entry.Hits-entry.Error > exact[entry.Value] || entry.Hits < exact[entry.Value] || entry.Error > bound {
+			t.Errorf("Top()[%v] = %+v, true hits %v, bound %v", i, entry, exact[entry.Value], bound)
+		}
+	}
+}
+
+func TestTopKMerge(t *testing.T) {
+	first, second := NewTopK(10), NewTopK(10)
+	for _, value := range []string{"/a", "/b", "/a"} {
+		first.Add(value)
+	}
+	for _, value := range []string{"/c", "/a", "/c", "/c"} {
+		second.Add(value)
+	}
+
+	merged := NewTopK(10)
+	merged.Merge(first)
+	merged.Merge(second)
+	want := []TopKEntry{{Value: "/a", Hits: 3}, {Value: "/c", Hits: 3}, {Value: "/b", Hits: 1}}
+	if got := merged.Top(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top() = %+v, want %+v", got, want)
+	}
+	if got := merged.ErrorBound(); got != 0 {
+		t.Errorf("ErrorBound() = %v, want 0", got)
+	}
+	if got := merged.Total(); got != 7 {
+		t.Errorf("Total() = %v, want 7", got)
+	}
+}
+
+func TestTopKMergeHeavyHitters(t *testing.T) {
+	// the stream of TestTopKHeavyHitters cut into ten seconds with a sketch each
+	exact := make(map[string]int)
+	merged := NewTopK(50)
+	for second := 0; second < 10; second++ {
+		sketch := NewTopK(50)
+		for index := second * 500; index < (second+1)*500; index++ {
+			value := fmt.Sprintf("/users/%d", index)
+			switch {
+			case index%10 == 0:
+				value = "/report"
+			case index%20 == 1:
+				value = "/api"
+			case index%25 == 2:
+				value = "/login"
+			}
+			exact[value]++
+			sketch.Add(value)
+		}
+		merged.Merge(sketch)
+	}
+
+	if got := merged.Total(); got != 5000 {
+		t.Errorf("Total() = %v, want 5000", got)
+	}
+	bound := merged.ErrorBound()
+	top := merged.Top(3)
+	wantValues := []string{"/report", "/api", "/login"}
+	for i, entry := range top {
+		if entry.Value != wantValues[i] {
+			t.Errorf("Top()[%v] = %v, want %v", i, entry.Value, wantValues[i])
+		}
+		// the true count is within the entry's error, which is within the bound
+		if entry.Hits-entry.Error > exact[entry.Value] || entry.Hits < exact[entry.Value] || entry.Error > bound {
+			t.Errorf("Top()[%v] = %+v, true hits %v, bound %v", i, entry, exact[entry.Value], bound)
+		}
+	}
+}
+
+func BenchmarkTopK(b *testing.B) {
+	values := make([]string, 100000)
+	for index := range values {
+		values[index] = fmt.Sprintf("/users/%d", index%20000)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sketch := NewTopK(1000)
+		for _, value := range values {
+			sketch.Add(value)
+		}
+		sketch.Top(5)
+	}
+}
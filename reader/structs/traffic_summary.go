@@ -0,0 +1,105 @@
+package structs
+
+import (
+	"sort"
+)
+
+// StatusCount is the number of hits of a status code
+type StatusCount struct {
+	Status int
+	Hits   int
+}
+
+/*
+TrafficSummary holds the totals of the traffic as a whole (every section) of a window: hits and bytes, hits by
+status class (Classes[2] are the 2xx hits...), by verb and by status code
+*/
+type TrafficSummary struct {
+	Hits     int
+	Bytes    int
+	Classes  [6]int
+	Verbs    map[string]int
+	Statuses map[int]int
+}
+
+// Add counts the event
+func (summary *TrafficSummary) Add(event LogEvent) {
+	summary.Hits++
+	summary.Bytes += event.ByteSize
+	if class := event.StatusCode / 100; class > 0 && class < len(summary.Classes) {
+		summary.Classes[class]++
+	}
+
+	if summary.Verbs == nil {
+		summary.Verbs = make(map[string]int)
+		summary.Statuses = make(map[int]int)
+	}
+	summary.Verbs[event.Verb]++
+	summary.Statuses[event.StatusCode]++
+}
+
+// Merge adds the totals of other
+func (summary *TrafficSummary) Merge(other TrafficSummary) {
+	summary.Hits += other.Hits
+	summary.Bytes += other.Bytes
+	for class, hits := range other.Classes {
+		summary.Classes[class] += hits
+	}
+
+	if other.Verbs == nil {
+		return
+	}
+	if summary.Verbs == nil {
+		summary.Verbs = make(map[string]int)
+		summary.Statuses = make(map[int]int)
+	}
+	for verb, hits := range other.Verbs {
+		summary.Verbs[verb] += hits
+	}
+	for status, hits := range other.Statuses {
+		summary.Statuses[status] += hits
+	}
+}
+
+// RequestRate returns the requests per second over a window of seconds seconds
+func (summary TrafficSummary) RequestRate(seconds int) float64 {
+	if seconds < 1 {
+		return 0
+	}
+	return float64(summary.Hits) / float64(seconds)
+}
+
+// Bandwidth returns the bytes per second over a window of seconds seconds
+func (summary TrafficSummary) Bandwidth(seconds int) float64 {
+	if seconds < 1 {
+		return 0
+	}
+	return float64(summary.Bytes) / float64(seconds)
+}
+
+// AverageBytes returns the average size of a response, 0 when there are none
+func (summary TrafficSummary) AverageBytes() float64 {
+	if summary.Hits == 0 {
+		return 0
+	}
+	return float64(summary.Bytes) / float64(summary.Hits)
+}
+
+// TopStatuses returns at most limit status codes, most hits first (ties are ordered by status code)
+func (summary TrafficSummary) TopStatuses(limit int) []StatusCount {
+	statuses := make([]StatusCount, 0, len(summary.Statuses))
+	for status, hits := range summary.Statuses {
+		statuses = append(statuses, StatusCount{Status: status, Hits: hits})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Hits == statuses[j].Hits {
+			return statuses[i].Status < statuses[j].Status
+		}
+		return statuses[i].Hits > statuses[j].Hits
+	})
+
+	if limit > 0 && len(statuses) > limit {
+		statuses = statuses[:limit]
+	}
+	return statuses
+}
@@ -0,0 +1,87 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrafficSummary(t *testing.T) {
+	events := []LogEvent{
+		{Verb: "GET", StatusCode: 200, ByteSize: 100},
+		{Verb: "GET", StatusCode: 200, ByteSize: 300},
+		{Verb: "POST", StatusCode: 201, ByteSize: 50},
+		{Verb: "GET", StatusCode: 304},
+		{Verb: "GET", StatusCode: 404, ByteSize: 20},
+		{Verb: "DELETE", StatusCode: 500, ByteSize: 30},
+	}
+
+	// splitting the events over two summaries and merging them adds up the same
+	first := TrafficSummary{}
+	second := TrafficSummary{}
+	for i, event := range events {
+		if i < 2 {
+			first.Add(event)
+		} else {
+			second.Add(event)
+		}
+	}
+	summary := TrafficSummary{}
+	summary.Merge(first)
+	summary.Merge(second)
+	summary.Merge(TrafficSummary{})
+
+	if summary.Hits != 6 || summary.Bytes != 500 {
+		t.Errorf("summary = %+v, want 6 hits and 500 bytes", summary)
+	}
+	if want := [6]int{0, 0, 3, 1, 1, 1}; summary.Classes != want {
+		t.Errorf("Classes = %v, want %v", summary.Classes, want)
+	}
+	if want := map[string]int{"GET": 4, "POST": 1, "DELETE": 1}; !reflect.DeepEqual(summary.Verbs, want) {
+		t.Errorf("Verbs = %v, want %v", summary.Verbs, want)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"RequestRate", summary.RequestRate(2), 3.0},
+		{"RequestRate of no window", summary.RequestRate(0), 0.0},
+		{"Bandwidth", summary.Bandwidth(10), 50.0},
+		{"AverageBytes", summary.AverageBytes(), 500.0 / 6},
+		{"AverageBytes of nothing", TrafficSummary{}.AverageBytes(), 0.0},
+		{"TopStatuses", summary.TopStatuses(3), []StatusCount{{200, 2}, {201, 1}, {304, 1}}},
+		{"TopStatuses of nothing", TrafficSummary{}.TopStatuses(3), []StatusCount{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventStoreSummary(t *testing.T) {
+	now := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+	defer withEventClock(now)()
+
+	store := NewEventStore(120)
+	for seconds := 60; seconds >= 0; seconds-- {
+		status := 200
+		if seconds%10 == 0 {
+			status = 500
+		}
+		store.Add(LogEvent{Date: now.Add(-time.Duration(seconds) * time.Second), Verb: "GET", StatusCode: status, ByteSize: 10})
+	}
+
+	recent := store.Summary(10)
+	if recent.Hits != 11 || recent.Bytes != 110 || recent.Classes[5] != 2 {
+		t.Errorf("Summary(10) = %+v, want 11 hits, 110 bytes and 2 5xx", recent)
+	}
+	all := store.Summary(120)
+	if all.Hits != 61 || all.Statuses[500] != 7 || all.Verbs["GET"] != 61 {
+		t.Errorf("Summary(120) = %+v, want 61 GETs, 7 of them 500", all)
+	}
+}
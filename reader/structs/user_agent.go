@@ -0,0 +1,120 @@
+package structs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// the classes a user agent can be put in
+const (
+	AgentBrowser = "browser"
+	AgentMobile  = "mobile"
+	AgentBot     = "bot"
+	AgentCLI     = "cli"
+	AgentUnknown = "unknown"
+)
+
+/*
+defaultUserAgentRules is the rule table used unless -userAgentRules loads another with ReadUserAgentRules.
+Each line is class<TAB>family<TAB>regex and the first regex matching the user agent wins, so more specific
+rules (bots pretending to be browsers, Edge pretending to be Chrome...) come first
+*/
+const defaultUserAgentRules = `# class	family	regex
+bot	Googlebot	Googlebot
+bot	Bingbot	(?i)bingbot
+bot	Baiduspider	Baiduspider
+bot	YandexBot	YandexBot
+bot	DuckDuckBot	DuckDuckBot
+bot	Yahoo Slurp	Yahoo! Slurp
+bot	Applebot	Applebot
+bot	Facebook	facebookexternalhit
+bot	Twitterbot	Twitterbot
+bot	AhrefsBot	AhrefsBot
+bot	SemrushBot	SemrushBot
+bot	Other Bot	(?i)bot\b|crawl|spider|slurp
+cli	curl	^curl/
+cli	Wget	^Wget/
+cli	HTTPie	^HTTPie/
+cli	Postman	^PostmanRuntime/
+cli	Python	^(python-requests|Python-urllib|aiohttp)/
+cli	Go	^Go-http-client/
+cli	Java	^(Java|Apache-HttpClient|okhttp)/
+cli	Kubernetes Probe	^kube-probe/
+mobile	Edge Mobile	EdgA/|EdgiOS/
+mobile	Chrome Mobile	Android.*Chrome/|CriOS/
+mobile	Firefox Mobile	Android.*Firefox/|FxiOS/
+mobile	Mobile Safari	(iPhone|iPad|iPod).*Safari/
+mobile	Other Mobile	Mobile|Android
+browser	Edge	Edge?/
+browser	Opera	OPR/|Opera
+browser	Firefox	Firefox/
+browser	Chrome	Chrome/|Chromium/
+browser	Safari	Safari/
+browser	Internet Explorer	MSIE |Trident/
+browser	Other Browser	^Mozilla/
+`
+
+// UserAgentRule puts the user agents matching Pattern in Class with the name Family
+type UserAgentRule struct {
+	Class   string
+	Family  string
+	Pattern *regexp.Regexp
+}
+
+// UserAgentRules are tried in order by ClassifyUserAgent
+var UserAgentRules = mustReadUserAgentRules(defaultUserAgentRules)
+
+// ReadUserAgentRules reads a rule table where each line is class<TAB>family<TAB>regex (blank lines and # comments are skipped)
+func ReadUserAgentRules(reader io.Reader) ([]UserAgentRule, error) {
+	rules := make([]UserAgentRule, 0)
+	scanner := bufio.NewScanner(reader)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pieces := strings.SplitN(line, "\t", 3)
+		if len(pieces) != 3 {
+			return nil, fmt.Errorf("Line %d: expected class<TAB>family<TAB>regex", number)
+		}
+		switch pieces[0] {
+		case AgentBrowser, AgentMobile, AgentBot, AgentCLI, AgentUnknown:
+		default:
+			return nil, fmt.Errorf("Line %d: unknown class %q", number, pieces[0])
+		}
+		pattern, err := regexp.Compile(pieces[2])
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", number, err)
+		}
+		rules = append(rules, UserAgentRule{Class: pieces[0], Family: pieces[1], Pattern: pattern})
+	}
+	return rules, scanner.Err()
+}
+
+// mustReadUserAgentRules reads the rules shipped with logtop, which are known to be good
+func mustReadUserAgentRules(table string) []UserAgentRule {
+	rules, err := ReadUserAgentRules(strings.NewReader(table))
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// ClassifyUserAgent returns the class (browser, mobile, bot, cli or unknown) and family of a user agent
+func ClassifyUserAgent(userAgent string) (class string, family string) {
+	if userAgent == "" {
+		return AgentUnknown, ""
+	}
+	for _, rule := range UserAgentRules {
+		if rule.Pattern.MatchString(userAgent) {
+			return rule.Class, rule.Family
+		}
+	}
+	return AgentUnknown, "Other"
+}
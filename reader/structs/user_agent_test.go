@@ -0,0 +1,47 @@
+package structs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent  string
+		wantClass  string
+		wantFamily string
+	}{
+		{userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", wantClass: AgentBot, wantFamily: "Googlebot"},
+		{userAgent: "Mozilla/5.0 (compatible; SomeNewCrawler/1.0)", wantClass: AgentBot, wantFamily: "Other Bot"},
+		{userAgent: "curl/7.64.1", wantClass: AgentCLI, wantFamily: "curl"},
+		{userAgent: "python-requests/2.22.0", wantClass: AgentCLI, wantFamily: "Python"},
+		{userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 12_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1", wantClass: AgentMobile, wantFamily: "Mobile Safari"},
+		{userAgent: "Mozilla/5.0 (Linux; Android 9; Pixel 3) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/73.0.3683.90 Mobile Safari/537.36", wantClass: AgentMobile, wantFamily: "Chrome Mobile"},
+		{userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.18362", wantClass: AgentBrowser, wantFamily: "Edge"},
+		{userAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/73.0.3683.86 Safari/537.36", wantClass: AgentBrowser, wantFamily: "Chrome"},
+		{userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:66.0) Gecko/20100101 Firefox/66.0", wantClass: AgentBrowser, wantFamily: "Firefox"},
+		{userAgent: "", wantClass: AgentUnknown, wantFamily: ""},
+		{userAgent: "SomethingElse", wantClass: AgentUnknown, wantFamily: "Other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			class, family := ClassifyUserAgent(tt.userAgent)
+			if class != tt.wantClass || family != tt.wantFamily {
+				t.Errorf("ClassifyUserAgent() = %v, %v, want %v, %v", class, family, tt.wantClass, tt.wantFamily)
+			}
+		})
+	}
+}
+
+func TestReadUserAgentRules(t *testing.T) {
+	rules, err := ReadUserAgentRules(strings.NewReader("# comment\n\ncli\tInternal\t^our-tool/\n"))
+	if err != nil || len(rules) != 1 || rules[0].Family != "Internal" {
+		t.Errorf("ReadUserAgentRules() = %v, %v", rules, err)
+	}
+
+	for _, table := range []string{"cli our-tool", "robot\tX\tx", "cli\tX\t("} {
+		if _, err := ReadUserAgentRules(strings.NewReader(table)); err == nil {
+			t.Errorf("ReadUserAgentRules(%q) expected an error", table)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package structs
+
+/*
+Visitors estimates the number of unique client hosts and unique users of a set of events with a HyperLogLog each,
+made the first time there is something to count
+*/
+type Visitors struct {
+	hosts *HyperLogLog
+	users *HyperLogLog
+}
+
+// Add counts the host and user of the event ("-" is what the log has when there is no user)
+func (visitors *Visitors) Add(event LogEvent) {
+	addUnique(&visitors.hosts, event.Host)
+	if event.User != "-" {
+		addUnique(&visitors.users, event.User)
+	}
+}
+
+// addUnique adds value to the HyperLogLog, making it first if needed, unless value is empty
+func addUnique(sketch **HyperLogLog, value string) {
+	if value == "" {
+		return
+	}
+	if *sketch == nil {
+		*sketch = NewHyperLogLog()
+	}
+	(*sketch).Add(value)
+}
+
+// Merge adds the hosts and users of other
+func (visitors *Visitors) Merge(other Visitors) {
+	mergeUnique(&visitors.hosts, other.hosts)
+	mergeUnique(&visitors.users, other.users)
+}
+
+// mergeUnique merges other into the HyperLogLog, making it first if needed
+func mergeUnique(sketch **HyperLogLog, other *HyperLogLog) {
+	if other == nil {
+		return
+	}
+	if *sketch == nil {
+		*sketch = NewHyperLogLog()
+	}
+	(*sketch).Merge(other)
+}
+
+// Hosts returns the estimated number of unique client hosts
+func (visitors Visitors) Hosts() int {
+	if visitors.hosts == nil {
+		return 0
+	}
+	return visitors.hosts.Count()
+}
+
+// Users returns the estimated number of unique users
+func (visitors Visitors) Users() int {
+	if visitors.users == nil {
+		return 0
+	}
+	return visitors.users.Count()
+}
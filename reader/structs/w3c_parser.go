@@ -0,0 +1,137 @@
+package structs
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDirective is returned for W3C header lines (#Version, #Fields...) which configure the parser rather than describe a request
+var ErrDirective = errors.New("Directive")
+
+// w3cDateLayout is the layout of the date and time columns joined by a space (always UTC per the spec)
+const w3cDateLayout = "2006-01-02 15:04:05"
+
+func init() {
+	RegisterParser("w3c", func() Parser { return &W3CParser{} })
+}
+
+/*
+W3CParser parses the W3C Extended Log File Format (https://www.w3.org/TR/WD-logfile.html) as written by IIS
+and CloudFront. The columns of each line are described by the most recent #Fields directive, so the
+parser is stateful and a server restart writing a new header mid-file is picked up
+*/
+type W3CParser struct {
+	fields []string
+}
+
+/*
+Parse takes the log string and returns a LogEvent
+
+A log could look like:
+#Version: 1.0
+#Fields: date time c-ip cs-username cs-method cs-uri-stem sc-status sc-bytes time-taken
+2019-03-23 18:44:53 127.0.0.1 frank GET /config/update 200 491 15
+*/
+func (parser *W3CParser) Parse(line string) (LogEvent, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return LogEvent{}, newParseError(RejectEmpty, "Empty String")
+	}
+
+	if strings.HasPrefix(line, "#") {
+		if strings.HasPrefix(line, "#Fields:") {
+			parser.fields = strings.Fields(strings.TrimPrefix(line, "#Fields:"))
+		}
+		return LogEvent{}, ErrDirective
+	}
+
+	if len(parser.fields) == 0 {
+		return LogEvent{}, newParseError(RejectMismatch, "Missing #Fields directive")
+	}
+
+	// IIS separates values with spaces, CloudFront with tabs - neither allows either inside a value
+	values := strings.Fields(line)
+	if len(values) != len(parser.fields) {
+		return LogEvent{}, newParseError(RejectMismatch, "Expected %d fields, got %d", len(parser.fields), len(values))
+	}
+
+	event := LogEvent{}
+	var date, clock string
+	for i, field := range parser.fields {
+		value := values[i]
+		if value == "-" {
+			continue
+		}
+
+		switch strings.ToLower(field) {
+		case "date":
+			date = value
+		case "time":
+			clock = value
+		case "c-ip":
+			event.Host = value
+			event.Address = ParseAddress(value)
+		case "cs-username":
+			event.User = value
+		case "cs-method":
+			event.Verb = value
+		case "cs-uri-stem":
+			event.Path = value
+		case "cs-uri-query":
+			event.setRawQuery(value)
+		case "cs-uri":
+			event.setTarget(value)
+		case "cs-version", "cs-protocol-version":
+			event.Protocol = value
+		case "sc-status":
+			status, err := strconv.Atoi(value)
+			if err != nil {
+				return LogEvent{}, newParseError(RejectStatus, "Bad status %q", value)
+			}
+			event.StatusCode = status
+		case "sc-bytes":
+			event.ByteSize, _ = strconv.Atoi(value)
+		case "time-taken":
+			event.Duration = ParseResponseTime(value)
+		case "cs(referer)", "cs(referrer)":
+			event.Referer = w3cUnescape(value)
+		case "cs(user-agent)":
+			event.UserAgent = w3cUnescape(value)
+		}
+	}
+
+	// without a date the event cannot be placed in any window
+	if date == "" || clock == "" {
+		return LogEvent{}, newParseError(RejectDate, "Missing date or time")
+	}
+	parsed, err := time.Parse(w3cDateLayout, date+" "+clock)
+	if err != nil {
+		return LogEvent{}, newParseError(RejectDate, "Bad date %q", date+" "+clock)
+	}
+	event.Date = parsed
+
+	event.Section = SectionFromPath(event.Path)
+	event.Error = event.StatusCode >= 400
+	return event, nil
+}
+
+/*
+w3cUnescape undoes the escaping IIS (spaces as '+') and CloudFront (URL encoding, which is applied twice
+to user agents) apply to header values
+*/
+func w3cUnescape(value string) string {
+	if !strings.Contains(value, "%") {
+		return strings.ReplaceAll(value, "+", " ")
+	}
+	for i := 0; i < 2 && strings.Contains(value, "%"); i++ {
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			break
+		}
+		value = unescaped
+	}
+	return value
+}
@@ -0,0 +1,133 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestW3CParserParse(t *testing.T) {
+	date := time.Date(2019, 3, 23, 18, 44, 53, 0, time.UTC)
+
+	// lines are fed to the same parser in order, just like they would be read from the log
+	tests := []struct {
+		name string
+		line string
+		// unit is the ResponseTimeUnit of time-taken, seconds when not set
+		unit    time.Duration
+		want    LogEvent
+		wantErr bool
+	}{
+		{
+			name:    "Line before any #Fields",
+			line:    "2019-03-23 18:44:53 127.0.0.1 GET /config 200",
+			wantErr: true,
+		},
+		{
+			name:    "Version directive",
+			line:    "#Version: 1.0",
+			wantErr: true,
+		},
+		{
+			name:    "IIS fields directive",
+			line:    "#Fields: date time c-ip cs-username cs-method cs-uri-stem sc-status sc-bytes time-taken cs(User-Agent)",
+			wantErr: true,
+		},
+		{
+			name: "IIS line",
+			line: "2019-03-23 18:44:53 127.0.0.1 frank GET /config/update 401 491 15 Mozilla/5.0+(Windows+NT+10.0)",
+			unit: time.Millisecond,
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				User:       "frank",
+				Date:       date,
+				Verb:       "GET",
+				Section:    "/config",
+				Path:       "/config/update",
+				StatusCode: 401,
+				ByteSize:   491,
+				Error:      true,
+				UserAgent:  "Mozilla/5.0 (Windows NT 10.0)",
+				Duration:   15 * time.Millisecond,
+			},
+		},
+		{
+			name: "Missing values",
+			line: "2019-03-23 18:44:53 127.0.0.1 - GET /config 200 - - -",
+			want: LogEvent{
+				Host:       "127.0.0.1",
+				Address:    ParseAddress("127.0.0.1"),
+				Date:       date,
+				Verb:       "GET",
+				Section:    "/config",
+				Path:       "/config",
+				StatusCode: 200,
+			},
+		},
+		{
+			name:    "Wrong number of values",
+			line:    "2019-03-23 18:44:53 127.0.0.1 frank GET /config 200",
+			wantErr: true,
+		},
+		{
+			name:    "New fields after a restart",
+			line:    "#Fields: date time x-edge-location sc-bytes c-ip cs-method cs-uri-stem sc-status cs(Referer) cs(User-Agent) time-taken",
+			wantErr: true,
+		},
+		{
+			name: "CloudFront line",
+			line: "2019-03-23\t18:44:53\tIAD79-C3\t2390\t192.0.2.100\tGET\t/api/user\t503\thttp://example.com/\tMozilla/5.0%2520(Macintosh)\t0.002",
+			want: LogEvent{
+				Host:       "192.0.2.100",
+				Address:    ParseAddress("192.0.2.100"),
+				Date:       date,
+				Verb:       "GET",
+				Section:    "/api",
+				Path:       "/api/user",
+				StatusCode: 503,
+				ByteSize:   2390,
+				Error:      true,
+				Referer:    "http://example.com/",
+				UserAgent:  "Mozilla/5.0 (Macintosh)",
+				Duration:   2 * time.Millisecond,
+			},
+		},
+		{
+			name:    "Bad status",
+			line:    "2019-03-23\t18:44:53\tIAD79-C3\t2390\t192.0.2.100\tGET\t/api/user\tOK\t-\t-\t0.002",
+			wantErr: true,
+		},
+		{
+			name:    "Fields without a date",
+			line:    "#Fields: c-ip cs-method cs-uri-stem sc-status",
+			wantErr: true,
+		},
+		{
+			name:    "Line without a date",
+			line:    "192.0.2.100 GET /api/user 200",
+			wantErr: true,
+		},
+	}
+
+	parser, err := LookupParser("w3c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.unit != 0 {
+				// IIS writes time-taken in milliseconds
+				ResponseTimeUnit = tt.unit
+				defer func() { ResponseTimeUnit = time.Second }()
+			}
+			got, err := parser.Parse(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}